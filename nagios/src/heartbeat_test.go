@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/arminc/opsgenie-heartbeat/script_monitor/src/opsgenie"
+)
+
+func runWithSendError(sendErr error, args pluginArgs) (int, string, bool) {
+	state, msg, attempts := runWithSendAttempts(sendErr, args)
+	return state, msg, attempts > 0
+}
+
+func runWithSendAttempts(sendErr error, args pluginArgs) (int, string, int) {
+	defer func(original func(opsgenie.OpsArgs) error) { sendHeartbeat = original }(sendHeartbeat)
+	attempts := 0
+	sendHeartbeat = func(opsgenie.OpsArgs) error {
+		attempts++
+		return sendErr
+	}
+	state, msg := run(args)
+	return state, msg, attempts
+}
+
+func TestRunStates(t *testing.T) {
+	tests := []struct {
+		err   error
+		state int
+	}{
+		{nil, stateOK},
+		{opsgenie.ErrorResponse{Code: 17, Message: "test error"}, stateWarning},
+		{errors.New("connection refused"), stateCritical},
+	}
+	for _, test := range tests {
+		state, msg, sent := runWithSendError(test.err, pluginArgs{})
+		if state != test.state || !sent {
+			t.Errorf("Error [%v] gave state [%d] and message [%s] but should be [%d]", test.err, state, msg, test.state)
+		}
+	}
+}
+
+func TestRunRetries(t *testing.T) {
+	args := pluginArgs{retries: 2, retryDelay: time.Millisecond}
+	if state, _, attempts := runWithSendAttempts(errors.New("connection refused"), args); state != stateCritical || attempts != 3 {
+		t.Errorf("Failed send gave state [%d] after [%d] attempts but should be retried twice", state, attempts)
+	}
+	if _, _, attempts := runWithSendAttempts(opsgenie.ErrorResponse{Code: 17}, args); attempts != 1 {
+		t.Errorf("OpsGenie error response retried [%d] times", attempts-1)
+	}
+}
+
+func TestRunDoesNotSendWhenStatusFileMissing(t *testing.T) {
+	state, _, sent := runWithSendError(nil, pluginArgs{statusFile: "/nonexistent/status.dat"})
+	if state != stateUnknown || sent {
+		t.Errorf("Heartbeat sent or wrong state [%d] for missing status file", state)
+	}
+}
+
+func TestRunDoesNotSendWhenSchedulerStale(t *testing.T) {
+	path := writeStatusFile(t, testStatus)
+	defer os.Remove(path)
+
+	state, _, sent := runWithSendError(nil, pluginArgs{statusFile: path})
+	if state != stateCritical || sent {
+		t.Errorf("Heartbeat sent or wrong state [%d] for stale scheduler", state)
+	}
+}
+
+func TestRunVerifiesCertificates(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+	defer opsgenie.SetAPIURL("https://api.opsgenie.com")
+
+	state, msg := run(pluginArgs{opsArgs: opsgenie.OpsArgs{ApiKey: "key", Name: "nagios"}, apiURL: server.URL})
+	if state != stateCritical {
+		t.Errorf("Heartbeat sent to a server with an untrusted certificate [%d] [%s]", state, msg)
+	}
+}
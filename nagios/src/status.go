@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schedulerStatus holds the parts of a Nagios/Icinga status.dat file that tell
+// us whether the scheduler is still running checks.
+type schedulerStatus struct {
+	checkExternalCommands bool
+	lastCommandCheck      time.Time
+	lastCheck             time.Time
+}
+
+func readSchedulerStatus(path string) (*schedulerStatus, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	status := &schedulerStatus{}
+	foundProgramStatus := false
+	block := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasSuffix(line, "{") {
+			block = strings.TrimSpace(strings.TrimSuffix(line, "{"))
+			if block == "programstatus" {
+				foundProgramStatus = true
+			}
+			continue
+		}
+		if line == "}" {
+			block = ""
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key, value := parts[0], parts[1]
+		switch block {
+		case "programstatus":
+			switch key {
+			case "check_external_commands":
+				status.checkExternalCommands = value == "1"
+			case "last_command_check":
+				status.lastCommandCheck = parseTimestamp(value)
+			}
+		case "hoststatus", "servicestatus":
+			if key == "last_check" {
+				if t := parseTimestamp(value); t.After(status.lastCheck) {
+					status.lastCheck = t
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !foundProgramStatus {
+		return nil, fmt.Errorf("no programstatus block found in %s", path)
+	}
+	return status, nil
+}
+
+func parseTimestamp(value string) time.Time {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
+
+// checkScheduler returns an error when the scheduler has not executed a check, or
+// processed external commands while they are enabled, within maxAge of now.
+func checkScheduler(status *schedulerStatus, now time.Time, maxAge time.Duration) error {
+	if status.lastCheck.IsZero() {
+		return fmt.Errorf("no executed host or service checks found")
+	}
+	if age := now.Sub(status.lastCheck); age > maxAge {
+		return fmt.Errorf("last check was executed %s ago", age)
+	}
+	if status.checkExternalCommands {
+		if age := now.Sub(status.lastCommandCheck); status.lastCommandCheck.IsZero() || age > maxAge {
+			return fmt.Errorf("external commands were last processed %s ago", age)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+const testStatus = `# NAGIOS STATUS FILE
+info {
+	created=1500000000
+	}
+
+programstatus {
+	nagios_pid=1234
+	check_external_commands=1
+	last_command_check=1500000090
+	}
+
+hoststatus {
+	host_name=web01
+	last_check=1500000080
+	}
+
+servicestatus {
+	host_name=web01
+	service_description=HTTP
+	last_check=1500000095
+	}
+`
+
+func writeStatusFile(t *testing.T, content string) string {
+	file, err := ioutil.TempFile("", "status.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	return file.Name()
+}
+
+func TestReadSchedulerStatus(t *testing.T) {
+	path := writeStatusFile(t, testStatus)
+	defer os.Remove(path)
+
+	status, err := readSchedulerStatus(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.checkExternalCommands || status.lastCommandCheck.Unix() != 1500000090 || status.lastCheck.Unix() != 1500000095 {
+		t.Errorf("Status not parsed correctly [%+v]", status)
+	}
+}
+
+func TestReadSchedulerStatusWithoutProgramStatus(t *testing.T) {
+	path := writeStatusFile(t, "info {\n\tcreated=1500000000\n\t}\n")
+	defer os.Remove(path)
+
+	if _, err := readSchedulerStatus(path); err == nil {
+		t.Errorf("Expected an error for a file without programstatus")
+	}
+}
+
+func TestCheckScheduler(t *testing.T) {
+	status := &schedulerStatus{true, time.Unix(1500000090, 0), time.Unix(1500000095, 0)}
+
+	if err := checkScheduler(status, time.Unix(1500000100, 0), time.Minute); err != nil {
+		t.Errorf("Fresh scheduler reported as stale [%s]", err)
+	}
+	if err := checkScheduler(status, time.Unix(1500000300, 0), time.Minute); err == nil {
+		t.Errorf("Stale checks not detected")
+	}
+	status.lastCommandCheck = time.Time{}
+	if err := checkScheduler(status, time.Unix(1500000100, 0), time.Minute); err == nil {
+		t.Errorf("Stale command check not detected")
+	}
+	status.checkExternalCommands = false
+	if err := checkScheduler(status, time.Unix(1500000100, 0), time.Minute); err != nil {
+		t.Errorf("Command check should be ignored when external commands are disabled [%s]", err)
+	}
+}
@@ -10,7 +10,6 @@ import (
 )
 
 func main() {
-	log.SetLevel(log.WarnLevel)
 	app := cli.NewApp()
 	app.Name = path.Base(os.Args[0])
 	app.Version = "1.0"
@@ -18,5 +17,15 @@ func main() {
 	app.Author = "OpsGenie"
 	app.Flags = opsgenie.SharedFlags
 	app.Commands = opsgenie.Commands
-	app.Run(os.Args)
+	app.Before = func(c *cli.Context) error {
+		err := opsgenie.ConfigureLogging(c)
+		if err != nil {
+			return err
+		}
+		return opsgenie.ConfigureClient(c)
+	}
+	err := app.Run(os.Args)
+	if err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,159 @@
+package opsgenie
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const defaultPriority = "P3"
+
+//Alert is an alert created through the OpsGenie Alert API
+type Alert struct {
+	Message     string
+	Alias       string
+	Description string
+	Source      string
+	Priority    string
+	Tags        []string
+	Teams       []string
+	Details     map[string]string
+}
+
+func createAlert(args OpsArgs, alert Alert) error {
+	contentParams := map[string]interface{}{
+		"apiKey":  args.ApiKey,
+		"message": alert.Message,
+	}
+	if alert.Alias != "" {
+		contentParams["alias"] = alert.Alias
+	}
+	if alert.Description != "" {
+		contentParams["description"] = alert.Description
+	}
+	if alert.Source != "" {
+		contentParams["source"] = alert.Source
+	}
+	if alert.Priority != "" {
+		contentParams["priority"] = alert.Priority
+	}
+	if len(alert.Tags) > 0 {
+		contentParams["tags"] = alert.Tags
+	}
+	if len(alert.Teams) > 0 {
+		contentParams["teams"] = alert.Teams
+	}
+	if len(alert.Details) > 0 {
+		contentParams["details"] = alert.Details
+	}
+	return doOpsGenieHTTPRequestHandled("POST", "/v1/json/alert", nil, contentParams, requestFields("alert", args.Name), "Successfully created alert ["+alert.Alias+"]")
+}
+
+func closeAlert(args OpsArgs, alias string) error {
+	host, _ := os.Hostname()
+	contentParams := map[string]interface{}{
+		"apiKey": args.ApiKey,
+		"alias":  alias,
+		"source": host,
+	}
+	_, err := doOpsGenieHTTPRequest("POST", "/v1/json/alert/close", nil, contentParams, requestFields("alert", args.Name))
+	return err
+}
+
+//openAlert is the state of an alert created for a heartbeat, the next successful send of the heartbeat closes it
+type openAlert struct {
+	Alias string `json:"alias"`
+}
+
+//recordAlert remembers the alert in the stateDir of the heartbeat, without stateDir the alert is not closed by a send
+func recordAlert(args OpsArgs, alias string) error {
+	if args.StateDir == "" {
+		return nil
+	}
+	return writeState(stateFile(args.StateDir, "alert", args.Name), openAlert{alias})
+}
+
+//closeRecordedAlert closes the alert recorded for the heartbeat, when closing fails the record is kept so the next send
+//tries again
+func closeRecordedAlert(args OpsArgs) {
+	if args.StateDir == "" {
+		return
+	}
+	path := stateFile(args.StateDir, "alert", args.Name)
+	state := openAlert{}
+	if err := readState(path, &state); err != nil || state.Alias == "" {
+		return
+	}
+	fields := requestFields("alert", args.Name)
+	if err := closeAlert(args, state.Alias); err != nil {
+		log.WithFields(fields).Errorf("Could not close alert [%s]: %s", state.Alias, err)
+		return
+	}
+	log.WithFields(fields).Infof("Successfully closed alert [%s]", state.Alias)
+	os.Remove(path)
+}
+
+func validPriority(priority string) bool {
+	return len(priority) == 2 && priority[0] == 'P' && priority[1] >= '1' && priority[1] <= '5'
+}
+
+//parsePriorities parses entries like "2=P2", the exit code * sets the priority of all other exit codes
+func parsePriorities(entries []string) (map[string]string, error) {
+	priorities := make(map[string]string)
+	for _, entry := range entries {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || !validPriority(parts[1]) {
+			return nil, fmt.Errorf("Priority [%s] should look like 2=P2 with a priority from P1 to P5", entry)
+		}
+		if parts[0] != "*" {
+			if _, err := strconv.Atoi(parts[0]); err != nil {
+				return nil, fmt.Errorf("Priority [%s] should have an exit code or *", entry)
+			}
+		}
+		priorities[parts[0]] = parts[1]
+	}
+	return priorities, nil
+}
+
+func priorityFor(priorities map[string]string, code int) string {
+	if priority, ok := priorities[strconv.Itoa(code)]; ok {
+		return priority
+	}
+	if priority, ok := priorities["*"]; ok {
+		return priority
+	}
+	return defaultPriority
+}
+
+//tailBuffer is a writer that keeps the last size bytes written to it
+type tailBuffer struct {
+	mu   sync.Mutex
+	size int
+	data []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data = append(b.data, p...)
+	if len(b.data) > b.size {
+		b.data = append(b.data[:0], b.data[len(b.data)-b.size:]...)
+	}
+	return len(p), nil
+}
+
+//String returns the kept bytes without a partial utf-8 character at the start
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data := b.data
+	for len(data) > 0 && !utf8.RuneStart(data[0]) {
+		data = data[1:]
+	}
+	return string(data)
+}
@@ -0,0 +1,61 @@
+package opsgenie
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParsePriorities(t *testing.T) {
+	priorities, err := parsePriorities([]string{"2=P2", "124=P1", "*=P4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if priorityFor(priorities, 2) != "P2" || priorityFor(priorities, 124) != "P1" || priorityFor(priorities, 7) != "P4" {
+		t.Errorf("Priorities not correct [%v]", priorities)
+	}
+	priorities, _ = parsePriorities(nil)
+	if priorityFor(priorities, 1) != defaultPriority {
+		t.Errorf("Default priority not correct [%v]", priorities)
+	}
+	for _, entry := range []string{"2", "2=P6", "x=P1", "2=high"} {
+		if _, err := parsePriorities([]string{entry}); err == nil {
+			t.Errorf("Priority [%s] should be invalid", entry)
+		}
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	buffer := &tailBuffer{size: 5}
+	buffer.Write([]byte("abc"))
+	buffer.Write([]byte("defg"))
+	if buffer.String() != "cdefg" {
+		t.Errorf("Tail is [%s]", buffer.String())
+	}
+	buffer.Write([]byte("é"))
+	buffer.Write([]byte("abcd"))
+	if buffer.String() != "abcd" {
+		t.Errorf("Tail with partial character is [%s]", buffer.String())
+	}
+}
+
+func TestCreateAlert(t *testing.T) {
+	var content map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &content)
+		w.Write([]byte("{}"))
+	}))
+	defer stopTestServer(server)
+	SetAPIURL(server.URL)
+
+	err := createAlert(testargs, Alert{Message: "failed", Alias: "alias", Priority: "P2", Details: map[string]string{"exitCode": "2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content["alias"] != "alias" || content["priority"] != "P2" || content["apiKey"] != testargs.ApiKey || content["description"] != nil {
+		t.Errorf("Alert content not correct [%v]", content)
+	}
+}
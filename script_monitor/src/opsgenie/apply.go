@@ -0,0 +1,194 @@
+package opsgenie
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/codegangsta/cli"
+)
+
+const configMandatory = "[config] is mandatory"
+
+type fieldDiff struct {
+	Field string
+	From  interface{}
+	To    interface{}
+}
+
+//heartbeatChange is a single step of the plan, Action is create, update or prune
+type heartbeatChange struct {
+	Action   string
+	Name     string
+	Diffs    []fieldDiff
+	existing Heartbeat
+}
+
+func (config HeartbeatConfig) enabled() bool {
+	return config.Enabled == nil || *config.Enabled
+}
+
+//planHeartbeats compares the desired heartbeats with the existing ones, only fields set in the config are compared
+func planHeartbeats(desired []HeartbeatConfig, existing []Heartbeat, prune bool) []heartbeatChange {
+	existingByName := make(map[string]Heartbeat)
+	for _, heartbeat := range existing {
+		existingByName[heartbeat.Name] = heartbeat
+	}
+	desiredNames := make(map[string]bool)
+	var plan []heartbeatChange
+	for _, config := range desired {
+		desiredNames[config.Name] = true
+		heartbeat, ok := existingByName[config.Name]
+		if !ok {
+			plan = append(plan, heartbeatChange{Action: "create", Name: config.Name})
+			continue
+		}
+		var diffs []fieldDiff
+		if config.Description != "" && config.Description != heartbeat.Description {
+			diffs = append(diffs, fieldDiff{"description", heartbeat.Description, config.Description})
+		}
+		if config.Interval != 0 && config.Interval != heartbeat.Interval {
+			diffs = append(diffs, fieldDiff{"interval", heartbeat.Interval, config.Interval})
+		}
+		if config.IntervalUnit != "" && config.IntervalUnit != heartbeat.IntervalUnit {
+			diffs = append(diffs, fieldDiff{"intervalUnit", heartbeat.IntervalUnit, config.IntervalUnit})
+		}
+		if config.OwnerTeam != "" && config.OwnerTeam != heartbeat.OwnerTeam {
+			diffs = append(diffs, fieldDiff{"ownerTeam", heartbeat.OwnerTeam, config.OwnerTeam})
+		}
+		if config.AlertMessage != "" && config.AlertMessage != heartbeat.AlertMessage {
+			diffs = append(diffs, fieldDiff{"alertMessage", heartbeat.AlertMessage, config.AlertMessage})
+		}
+		if len(config.AlertTags) > 0 && !sameTags(config.AlertTags, heartbeat.AlertTags) {
+			diffs = append(diffs, fieldDiff{"alertTags", heartbeat.AlertTags, config.AlertTags})
+		}
+		if config.AlertPriority != "" && config.AlertPriority != heartbeat.AlertPriority {
+			diffs = append(diffs, fieldDiff{"alertPriority", heartbeat.AlertPriority, config.AlertPriority})
+		}
+		if config.enabled() != heartbeat.Enabled {
+			diffs = append(diffs, fieldDiff{"enabled", heartbeat.Enabled, config.enabled()})
+		}
+		if len(diffs) > 0 {
+			plan = append(plan, heartbeatChange{Action: "update", Name: config.Name, Diffs: diffs, existing: heartbeat})
+		}
+	}
+	if prune {
+		for _, heartbeat := range existing {
+			if !desiredNames[heartbeat.Name] {
+				plan = append(plan, heartbeatChange{Action: "prune", Name: heartbeat.Name, existing: heartbeat})
+			}
+		}
+	}
+	sort.Sort(byName(plan))
+	return plan
+}
+
+//sameTags compares tags ignoring their order
+func sameTags(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	count := make(map[string]int)
+	for _, tag := range a {
+		count[tag]++
+	}
+	for _, tag := range b {
+		count[tag]--
+		if count[tag] < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+type byName []heartbeatChange
+
+func (p byName) Len() int           { return len(p) }
+func (p byName) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p byName) Less(i, j int) bool { return p[i].Name < p[j].Name }
+
+func printPlan(w io.Writer, plan []heartbeatChange) {
+	counts := make(map[string]int)
+	for _, change := range plan {
+		counts[change.Action]++
+		switch change.Action {
+		case "create":
+			fmt.Fprintf(w, "+ create %s\n", change.Name)
+		case "update":
+			fmt.Fprintf(w, "~ update %s\n", change.Name)
+			for _, diff := range change.Diffs {
+				fmt.Fprintf(w, "    %s: %v -> %v\n", diff.Field, diff.From, diff.To)
+			}
+		case "prune":
+			fmt.Fprintf(w, "- prune %s\n", change.Name)
+		}
+	}
+	fmt.Fprintf(w, "Plan: %d to create, %d to update, %d to prune\n", counts["create"], counts["update"], counts["prune"])
+}
+
+func executeChange(config *Config, apiKey string, change heartbeatChange) error {
+	if change.Action == "prune" {
+		return deleteHeartbeat(OpsArgs{ApiKey: apiKey, Name: change.Name})
+	}
+	args, err := config.opsArgs(change.Name)
+	if err != nil {
+		return err
+	}
+	args.ApiKey = apiKey
+	heartbeatConfig, _ := config.heartbeat(change.Name)
+	if change.Action == "create" {
+		err = addHeartbeat(args)
+		if err == nil && !heartbeatConfig.enabled() {
+			err = disableHeartbeat(args)
+		}
+		return err
+	}
+	return updateHeartbeat(args, change.existing, heartbeatConfig.enabled())
+}
+
+//applyConfig reconciles the heartbeats in OpsGenie with the config file, with planOnly it exits with 2 when there is drift
+func applyConfig(c *cli.Context) {
+	if c.GlobalString("config") == "" {
+		logAndExit(configMandatory)
+		return
+	}
+	config, err := loadConfig(c.GlobalString("config"))
+	if err == nil {
+		err = config.templateErrors()
+	}
+	if err != nil {
+		logAndExit(err.Error())
+		return
+	}
+	apiKey := globalStringArg(c, "apiKey", config.ApiKey)
+	if apiKey == "" {
+		logAndExit(mandatoryFlags)
+		return
+	}
+	existing, err := listHeartbeats(apiKey)
+	if err != nil {
+		logAndExit(err.Error())
+		return
+	}
+	plan := planHeartbeats(config.Heartbeats, existing, c.Bool("prune"))
+	printPlan(os.Stdout, plan)
+	if c.Bool("planOnly") {
+		if len(plan) > 0 {
+			os.Exit(2)
+		}
+		return
+	}
+	failed := false
+	for _, change := range plan {
+		err := executeChange(config, apiKey, change)
+		if err != nil {
+			failed = true
+			log.WithFields(requestFields(change.Action, change.Name)).Error(err)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
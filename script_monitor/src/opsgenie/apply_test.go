@@ -0,0 +1,78 @@
+package opsgenie
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestPlanHeartbeats(t *testing.T) {
+	disabled := false
+	desired := []HeartbeatConfig{
+		{Name: "new"},
+		{Name: "same", Interval: 10, IntervalUnit: "minutes"},
+		{Name: "changed", Description: "new description", Interval: 5, Enabled: &disabled},
+	}
+	existing := []Heartbeat{
+		{Name: "same", Description: "not managed", Interval: 10, IntervalUnit: "minutes", Enabled: true},
+		{Name: "changed", Description: "old description", Interval: 5, IntervalUnit: "hours", Enabled: true},
+		{Name: "old", Enabled: true},
+	}
+
+	plan := planHeartbeats(desired, existing, false)
+	expected := []heartbeatChange{
+		{Action: "update", Name: "changed", Diffs: []fieldDiff{{"description", "old description", "new description"}, {"enabled", true, false}}, existing: existing[1]},
+		{Action: "create", Name: "new"},
+	}
+	if !reflect.DeepEqual(plan, expected) {
+		t.Errorf("Plan is [%+v] but should be [%+v]", plan, expected)
+	}
+
+	plan = planHeartbeats(desired, existing, true)
+	if len(plan) != 3 || plan[2].Action != "prune" || plan[2].Name != "old" {
+		t.Errorf("Plan with prune is [%+v]", plan)
+	}
+}
+
+func TestPlanHeartbeatsAlertSettings(t *testing.T) {
+	desired := []HeartbeatConfig{
+		{Name: "same", AlertTags: []string{"b", "a"}, OwnerTeam: "ops"},
+		{Name: "changed", AlertTags: []string{"a"}, AlertPriority: "P1", AlertMessage: "new"},
+	}
+	existing := []Heartbeat{
+		{Name: "same", AlertTags: []string{"a", "b"}, OwnerTeam: "ops", AlertPriority: "P3", Enabled: true},
+		{Name: "changed", AlertTags: []string{"b"}, AlertPriority: "P3", AlertMessage: "old", Enabled: true},
+	}
+
+	plan := planHeartbeats(desired, existing, false)
+	expected := []fieldDiff{{"alertMessage", "old", "new"}, {"alertTags", []string{"b"}, []string{"a"}}, {"alertPriority", "P3", "P1"}}
+	if len(plan) != 1 || !reflect.DeepEqual(plan[0].Diffs, expected) {
+		t.Errorf("Plan is [%+v] but should update changed with [%+v]", plan, expected)
+	}
+}
+
+func TestPlanHeartbeatsTemplatedAlertMessage(t *testing.T) {
+	config := &Config{
+		Labels:     map[string]string{"team": "db"},
+		Heartbeats: []HeartbeatConfig{{Name: "backup", AlertMessage: "Backup of {{.Labels.team}} missing"}},
+	}
+	config.expandTemplates()
+	existing := []Heartbeat{{Name: "backup", AlertMessage: "Backup of db missing", Enabled: true}}
+	if plan := planHeartbeats(config.Heartbeats, existing, false); len(plan) != 0 {
+		t.Errorf("Expanded alert message should not drift [%+v]", plan)
+	}
+}
+
+func TestPrintPlan(t *testing.T) {
+	plan := []heartbeatChange{
+		{Action: "update", Name: "changed", Diffs: []fieldDiff{{"interval", 10, 5}}},
+		{Action: "create", Name: "new"},
+		{Action: "prune", Name: "old"},
+	}
+	var out bytes.Buffer
+	printPlan(&out, plan)
+	expected := "~ update changed\n    interval: 10 -> 5\n+ create new\n- prune old\nPlan: 1 to create, 1 to update, 1 to prune\n"
+	if out.String() != expected {
+		t.Errorf("Printed plan is [%s] but should be [%s]", out.String(), expected)
+	}
+}
@@ -2,7 +2,9 @@ package opsgenie
 
 import (
 	"flag"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/codegangsta/cli"
 )
@@ -30,6 +32,54 @@ func TestAllKeysProvided(t *testing.T) {
 	}
 }
 
+func TestFlagWrongSchedule(t *testing.T) {
+	set, globalSet := createFlagSets("key", "name", "", "", 0, false)
+	set.Set("schedule", "every day")
+	flagsTestHelper(t, scheduleWrong+"Cron expression [every day] should have 5 fields: minute hour day-of-month month day-of-week", cli.NewContext(nil, set, globalSet))
+}
+
+func TestConfigFileWithFlagOverride(t *testing.T) {
+	path := writeConfigFile(t, testConfig)
+	defer os.Remove(path)
+
+	set, globalSet := createFlagSets("", "first", "", "", 0, false)
+	globalSet.Set("config", path)
+	set.Set("description", "from flag")
+	ops := extractArgs(cli.NewContext(nil, set, globalSet))
+	if ops.ApiKey != "configKey" || ops.Description != "from flag" || ops.Interval != 5 || ops.IntervalUnit != "hours" {
+		t.Errorf("OpsArgs struct not correct [%+v]", ops)
+	}
+}
+
+func TestConfigFileTemplateExpandedOnce(t *testing.T) {
+	os.Setenv("OPSGENIE_TEST_BRACES", "{{.Hostname}}")
+	defer os.Unsetenv("OPSGENIE_TEST_BRACES")
+	path := writeConfigFile(t, `{"apiKey": "configKey", "labels": {"team": "db"}, "heartbeats": [{"name": "first", "alertMessage": "{{.Env.OPSGENIE_TEST_BRACES}}"}]}`)
+	defer os.Remove(path)
+
+	set, globalSet := createFlagSets("", "first", "", "", 0, false)
+	globalSet.Set("config", path)
+	set.Set("description", "{{.Labels.team}} backup")
+	defer func(original func(string)) { logAndExit = original }(logAndExit)
+	logAndExit = func(msg string) { t.Errorf("Exited with [%s]", msg) }
+	ops := extractArgs(cli.NewContext(nil, set, globalSet))
+	if ops.AlertMessage != "{{.Hostname}}" || ops.Description != "db backup" {
+		t.Errorf("Config value expanded twice or flag not expanded [%+v]", ops)
+	}
+}
+
+func TestLoopIntervalDerived(t *testing.T) {
+	ops := extractArgs(createCliAll("apiKey", "name", "hours", "", 3, false))
+	if ops.LoopInterval != time.Hour {
+		t.Errorf("Loop interval is [%s] but should be a third of the heartbeat interval", ops.LoopInterval)
+	}
+	set, globalSet := createFlagSets("apiKey", "name", "hours", "", 3, false)
+	set.Set("loopInterval", "10m")
+	if ops = extractArgs(cli.NewContext(nil, set, globalSet)); ops.LoopInterval != 10*time.Minute {
+		t.Errorf("Loop interval flag not used [%s]", ops.LoopInterval)
+	}
+}
+
 func flagsTestHelper(t *testing.T, msg string, c *cli.Context) {
 	var incomingMsg string
 
@@ -49,13 +99,22 @@ func createCli(apiKey string, name string, intervalUnit string) *cli.Context {
 }
 
 func createCliAll(apiKey string, name string, intervalUnit string, description string, interval int, delete bool) *cli.Context {
+	set, globalSet := createFlagSets(apiKey, name, intervalUnit, description, interval, delete)
+	return cli.NewContext(nil, set, globalSet)
+}
+
+func createFlagSets(apiKey string, name string, intervalUnit string, description string, interval int, delete bool) (*flag.FlagSet, *flag.FlagSet) {
 	globalSet := flag.NewFlagSet("testGlobal", 0)
 	globalSet.String("apiKey", apiKey, "")
 	globalSet.String("name", name, "")
+	globalSet.String("config", "", "")
 	set := flag.NewFlagSet("test", 0)
 	set.String("description", description, "")
 	set.Int("interval", interval, "")
 	set.String("intervalUnit", intervalUnit, "")
 	set.Bool("delete", delete, "")
-	return cli.NewContext(nil, set, globalSet)
+	set.String("schedule", "", "")
+	set.String("timeZone", "", "")
+	set.Duration("loopInterval", 0, "")
+	return set, globalSet
 }
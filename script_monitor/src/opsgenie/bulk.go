@@ -0,0 +1,221 @@
+package opsgenie
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/codegangsta/cli"
+)
+
+var bulkFlags = []cli.Flag{
+	cli.StringSliceFlag{
+		Name:  "pattern, p",
+		Value: &cli.StringSlice{},
+		Usage: "Glob pattern for the heartbeat names, can be repeated",
+	},
+	cli.StringSliceFlag{
+		Name:  "regex, r",
+		Value: &cli.StringSlice{},
+		Usage: "Regular expression for the heartbeat names, can be repeated",
+	},
+	cli.StringFlag{
+		Name:  "namesFile, f",
+		Value: "",
+		Usage: "File with a heartbeat name per line",
+	},
+	cli.IntFlag{
+		Name:  "workers, w",
+		Value: 10,
+		Usage: "Number of heartbeats handled at the same time",
+	},
+}
+
+var bulkCommands = []cli.Command{
+	{
+		Name:        "stop",
+		Usage:       "Disables the selected heartbeats",
+		Description: "Disables every heartbeat selected with -pattern, -regex or -namesFile.",
+		Flags:       bulkFlags,
+		Action: func(c *cli.Context) {
+			bulk(c, func(args OpsArgs) error {
+				return disableHeartbeat(args)
+			})
+		},
+	},
+	{
+		Name:        "start",
+		Usage:       "Enables the selected heartbeats and sends a heartbeat",
+		Description: "Enables every heartbeat selected with -pattern, -regex or -namesFile and sends a heartbeat to activate it.",
+		Flags:       bulkFlags,
+		Action: func(c *cli.Context) {
+			bulk(c, func(args OpsArgs) error {
+				err := enableHeartbeat(args)
+				if err != nil {
+					return err
+				}
+				return sendHeartbeat(args)
+			})
+		},
+	},
+	{
+		Name:        "delete",
+		Usage:       "Deletes the selected heartbeats",
+		Description: "Deletes every heartbeat selected with -pattern, -regex or -namesFile.",
+		Flags:       bulkFlags,
+		Action: func(c *cli.Context) {
+			bulk(c, func(args OpsArgs) error {
+				return deleteHeartbeat(args)
+			})
+		},
+	},
+}
+
+type bulkResult struct {
+	Name string
+	Err  error
+}
+
+//selectHeartbeats returns the names matching a glob pattern or a regular expression, in the order of heartbeats
+func selectHeartbeats(heartbeats []Heartbeat, patterns []string, regexes []string) ([]string, error) {
+	var compiled []*regexp.Regexp
+	for _, expression := range regexes {
+		re, err := regexp.Compile(expression)
+		if err != nil {
+			return nil, fmt.Errorf("Regular expression [%s] is not valid: %s", expression, err)
+		}
+		compiled = append(compiled, re)
+	}
+	var names []string
+	for _, heartbeat := range heartbeats {
+		matched := false
+		if len(patterns) > 0 {
+			var err error
+			matched, err = matchesAny(heartbeat.Name, patterns)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, re := range compiled {
+			matched = matched || re.MatchString(heartbeat.Name)
+		}
+		if matched {
+			names = append(names, heartbeat.Name)
+		}
+	}
+	return names, nil
+}
+
+//readNames reads a name per line, empty lines and lines starting with # are skipped
+func readNames(r io.Reader) ([]string, error) {
+	var names []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if name != "" && !strings.HasPrefix(name, "#") {
+			names = append(names, name)
+		}
+	}
+	return names, scanner.Err()
+}
+
+//runBulk calls action for every name with at most workers at the same time, the results are in the order of names
+func runBulk(names []string, workers int, action func(name string) error) []bulkResult {
+	if workers < 1 {
+		workers = 1
+	}
+	results := make([]bulkResult, len(names))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				results[index] = bulkResult{names[index], action(names[index])}
+			}
+		}()
+	}
+	for index := range names {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+func printBulkResults(w io.Writer, results []bulkResult) int {
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(w, "FAIL %s: %s\n", result.Name, result.Err)
+		} else {
+			fmt.Fprintf(w, "OK   %s\n", result.Name)
+		}
+	}
+	fmt.Fprintf(w, "%d succeeded, %d failed\n", len(results)-failed, failed)
+	return failed
+}
+
+func bulkNames(c *cli.Context, apiKey string) ([]string, error) {
+	var names []string
+	if len(c.StringSlice("pattern")) > 0 || len(c.StringSlice("regex")) > 0 {
+		heartbeats, err := listHeartbeats(apiKey)
+		if err != nil {
+			return nil, err
+		}
+		names, err = selectHeartbeats(heartbeats, c.StringSlice("pattern"), c.StringSlice("regex"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.String("namesFile") != "" {
+		file, err := os.Open(c.String("namesFile"))
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		fileNames, err := readNames(file)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, fileNames...)
+	}
+	unique := make(map[string]bool)
+	var result []string
+	for _, name := range names {
+		if !unique[name] {
+			unique[name] = true
+			result = append(result, name)
+		}
+	}
+	return result, nil
+}
+
+func bulk(c *cli.Context, action func(args OpsArgs) error) {
+	apiKey, err := apiKeyArg(c)
+	if err != nil {
+		logAndExit(err.Error())
+		return
+	}
+	names, err := bulkNames(c, apiKey)
+	if err != nil {
+		logAndExit(err.Error())
+		return
+	}
+	if len(names) == 0 {
+		logAndExit("No heartbeats selected, use [pattern], [regex] or [namesFile]")
+		return
+	}
+	results := runBulk(names, c.Int("workers"), func(name string) error {
+		return action(OpsArgs{ApiKey: apiKey, Name: name})
+	})
+	if printBulkResults(os.Stdout, results) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,69 @@
+package opsgenie
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSelectHeartbeats(t *testing.T) {
+	heartbeats := []Heartbeat{{Name: "eu-west-1-web"}, {Name: "eu-west-2-db"}, {Name: "us-east-1-web"}, {Name: "eu-central-1-web"}}
+
+	names, err := selectHeartbeats(heartbeats, []string{"eu-west-*"}, []string{"^us-.*-web$"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"eu-west-1-web", "eu-west-2-db", "us-east-1-web"}) {
+		t.Errorf("Selected heartbeats are [%v]", names)
+	}
+	if _, err := selectHeartbeats(heartbeats, nil, []string{"(web"}); err == nil {
+		t.Errorf("Invalid regular expression accepted")
+	}
+}
+
+func TestReadNames(t *testing.T) {
+	names, err := readNames(strings.NewReader("first\n\n# comment\n  second  \n"))
+	if err != nil || !reflect.DeepEqual(names, []string{"first", "second"}) {
+		t.Errorf("Names are [%v] [%v]", names, err)
+	}
+}
+
+func TestRunBulkLimitsWorkers(t *testing.T) {
+	var mutex sync.Mutex
+	running, maxRunning := 0, 0
+	names := []string{"a", "b", "c", "d", "e", "f"}
+
+	results := runBulk(names, 2, func(name string) error {
+		mutex.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mutex.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+		if name == "c" {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	if maxRunning > 2 {
+		t.Errorf("[%d] workers ran at the same time", maxRunning)
+	}
+	for i, result := range results {
+		if result.Name != names[i] || (result.Err != nil) != (result.Name == "c") {
+			t.Errorf("Result [%d] not correct [%+v]", i, result)
+		}
+	}
+
+	var out bytes.Buffer
+	if failed := printBulkResults(&out, results); failed != 1 || !strings.Contains(out.String(), "FAIL c: failed") || !strings.Contains(out.String(), "5 succeeded, 1 failed") {
+		t.Errorf("Summary not correct [%s]", out.String())
+	}
+}
@@ -0,0 +1,85 @@
+package opsgenie
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//loopFraction is the part of the heartbeat interval used as default loop interval, leaving room for failed sends
+const loopFraction = 3
+
+//scheduleGapFires limits how many sends of a schedule that fires often are looked at to find the longest gap
+const scheduleGapFires = 1000
+
+var defaultLoopInterval = 60 * time.Second
+
+//heartbeatInterval returns the interval of the heartbeat as a duration, or 0 when it is unknown
+func heartbeatInterval(args OpsArgs) time.Duration {
+	unit, ok := intervalUnits[args.IntervalUnit]
+	if !ok || args.Interval <= 0 {
+		return 0
+	}
+	return time.Duration(args.Interval) * unit
+}
+
+//loopIntervalFor returns a fraction of the heartbeat interval, or the default loop interval when the interval is unknown
+func loopIntervalFor(args OpsArgs) time.Duration {
+	interval := heartbeatInterval(args)
+	if interval == 0 {
+		return defaultLoopInterval
+	}
+	return interval / loopFraction
+}
+
+//checkLoopInterval refuses a loop interval of zero or less, and a loop interval or the longest gap of the schedule that
+//with jitter lets the heartbeat expire between sends. It warns when a single failed send would let it expire.
+func checkLoopInterval(args OpsArgs) error {
+	if args.LoopInterval <= 0 {
+		return fmt.Errorf("[loopInterval] %s should be more than zero", args.LoopInterval)
+	}
+	interval := heartbeatInterval(args)
+	if interval == 0 {
+		return nil
+	}
+	loop, what := args.LoopInterval, fmt.Sprintf("[loopInterval] %s", args.LoopInterval)
+	if args.Schedule != "" {
+		var err error
+		if loop, err = scheduleGap(args.Schedule, args.TimeZone, time.Now()); err != nil {
+			return err
+		}
+		what = fmt.Sprintf("the longest gap %s of [schedule] %s", loop, args.Schedule)
+	}
+	gap := loop + args.Jitter
+	if gap >= interval {
+		return fmt.Errorf("%s plus [jitter] %s is not shorter than the heartbeat interval %s, the heartbeat would expire between sends", what, args.Jitter, interval)
+	}
+	if gap > interval/2 {
+		log.WithFields(requestFields("loop", args.Name)).Warnf("%s plus [jitter] %s is more than half the heartbeat interval %s, a single failed send lets the heartbeat expire", what, args.Jitter, interval)
+	}
+	return nil
+}
+
+//scheduleGap returns the longest time between two sends of the schedule in the year after now, looking at no more than
+//scheduleGapFires sends. It returns 0 for a schedule that fires at most once.
+func scheduleGap(expression string, timeZone string, now time.Time) (time.Duration, error) {
+	schedule, err := parseCron(expression, timeZone)
+	if err != nil {
+		return 0, err
+	}
+	var gap time.Duration
+	end := now.AddDate(1, 0, 0)
+	last := schedule.next(now)
+	for fires := 0; fires < scheduleGapFires && !last.IsZero() && last.Before(end); fires++ {
+		next := schedule.next(last)
+		if next.IsZero() {
+			break
+		}
+		if next.Sub(last) > gap {
+			gap = next.Sub(last)
+		}
+		last = next
+	}
+	return gap, nil
+}
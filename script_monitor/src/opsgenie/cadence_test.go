@@ -0,0 +1,71 @@
+package opsgenie
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoopIntervalFor(t *testing.T) {
+	if interval := loopIntervalFor(OpsArgs{Interval: 15, IntervalUnit: "minutes"}); interval != 5*time.Minute {
+		t.Errorf("Loop interval is [%s] but should be a third of 15 minutes", interval)
+	}
+	if interval := loopIntervalFor(OpsArgs{}); interval != defaultLoopInterval {
+		t.Errorf("Loop interval without heartbeat interval is [%s]", interval)
+	}
+}
+
+func TestCheckLoopInterval(t *testing.T) {
+	tests := []struct {
+		loopInterval time.Duration
+		valid        bool
+	}{
+		{time.Minute, true},
+		{4 * time.Minute, true},
+		{5 * time.Minute, false},
+		{10 * time.Minute, false},
+	}
+	for _, test := range tests {
+		args := OpsArgs{Interval: 5, IntervalUnit: "minutes", LoopInterval: test.loopInterval}
+		if err := checkLoopInterval(args); (err == nil) != test.valid {
+			t.Errorf("Loop interval [%s] gave [%v]", test.loopInterval, err)
+		}
+	}
+	if err := checkLoopInterval(OpsArgs{Interval: 5, IntervalUnit: "minutes", LoopInterval: 4 * time.Minute, Jitter: time.Minute}); err == nil {
+		t.Error("Loop interval plus jitter should be refused")
+	}
+	if err := checkLoopInterval(OpsArgs{Interval: 5, IntervalUnit: "minutes", LoopInterval: -time.Minute}); err == nil {
+		t.Error("Negative loop interval should be refused")
+	}
+	if err := checkLoopInterval(OpsArgs{LoopInterval: 0}); err == nil {
+		t.Error("Zero loop interval should be refused")
+	}
+}
+
+func TestCheckLoopIntervalSchedule(t *testing.T) {
+	tests := []struct {
+		schedule string
+		interval int
+		valid    bool
+	}{
+		{"*/2 * * * *", 5, true},
+		{"0 * * * *", 90, true},
+		{"0 * * * *", 60, false},
+		{"0 9 * * 1-5", 1440, false},
+	}
+	for _, test := range tests {
+		args := OpsArgs{Interval: test.interval, IntervalUnit: "minutes", LoopInterval: 24 * time.Hour, Schedule: test.schedule}
+		if err := checkLoopInterval(args); (err == nil) != test.valid {
+			t.Errorf("Schedule [%s] with interval [%d] minutes gave [%v]", test.schedule, test.interval, err)
+		}
+	}
+}
+
+func TestScheduleGap(t *testing.T) {
+	now := time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC)
+	if gap, err := scheduleGap("0 9 * * 1-5", "UTC", now); err != nil || gap != 72*time.Hour {
+		t.Errorf("Longest gap of weekdays is [%s] but should be the weekend [%v]", gap, err)
+	}
+	if _, err := scheduleGap("every day", "", now); err == nil {
+		t.Error("Invalid schedule accepted")
+	}
+}
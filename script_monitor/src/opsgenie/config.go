@@ -0,0 +1,176 @@
+package opsgenie
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/codegangsta/cli"
+)
+
+//Config represents the config file, values from the file are used when the matching flag is not set
+type Config struct {
+	ApiKey   string `json:"apiKey,omitempty"`
+	StateDir string `json:"stateDir,omitempty"`
+	Proxy    string `json:"proxy,omitempty"`
+	NoProxy  string `json:"noProxy,omitempty"`
+	//Labels can be used in the templates of heartbeat names and descriptions
+	Labels     map[string]string `json:"labels,omitempty"`
+	Heartbeats []HeartbeatConfig `json:"heartbeats"`
+}
+
+//HeartbeatConfig represents a heartbeat in the config file
+type HeartbeatConfig struct {
+	Name         string `json:"name"`
+	Description  string `json:"description,omitempty"`
+	Interval     int    `json:"interval,omitempty"`
+	IntervalUnit string `json:"intervalUnit,omitempty"`
+	LoopInterval string `json:"loopInterval,omitempty"`
+	Schedule     string `json:"schedule,omitempty"`
+	TimeZone     string `json:"timeZone,omitempty"`
+	LockFile     string `json:"lockFile,omitempty"`
+	LockURL      string `json:"lockUrl,omitempty"`
+	LeaseTime    string `json:"leaseTime,omitempty"`
+	Jitter       string `json:"jitter,omitempty"`
+	Stagger      bool   `json:"stagger,omitempty"`
+	//Enabled is only used by apply, a heartbeat is enabled when it is not set
+	Enabled *bool `json:"enabled,omitempty"`
+	//Labels override the labels with the same key from the config
+	Labels        map[string]string `json:"labels,omitempty"`
+	OwnerTeam     string            `json:"ownerTeam,omitempty"`
+	AlertMessage  string            `json:"alertMessage,omitempty"`
+	AlertTags     []string          `json:"alertTags,omitempty"`
+	AlertPriority string            `json:"alertPriority,omitempty"`
+	//RelayAllow lists the local users or uids that may ping the heartbeat through the relay, anyone when empty
+	RelayAllow []string `json:"relayAllow,omitempty"`
+	//Maintenance windows disable the heartbeat in the loops while they are active
+	Maintenance []MaintenanceWindow `json:"maintenance,omitempty"`
+
+	//templateErr is set when the templates of the heartbeat can't be expanded, it only fails commands using this heartbeat
+	templateErr error
+}
+
+func loadConfig(path string) (*Config, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &Config{}
+	err = json.Unmarshal(content, config)
+	if err != nil {
+		return nil, fmt.Errorf("Config file [%s] is not valid: %s", path, err)
+	}
+	config.expandTemplates()
+	return config, nil
+}
+
+//heartbeat returns the heartbeat with the given name, or the only heartbeat when name is empty. The names in the config
+//are expanded when it is loaded, name can be the expanded name or the template from the config.
+func (config *Config) heartbeat(name string) (*HeartbeatConfig, error) {
+	if name == "" {
+		if len(config.Heartbeats) == 1 {
+			return config.Heartbeats[0].expanded()
+		}
+		return nil, fmt.Errorf("[name] is mandatory when the config file does not contain exactly one heartbeat")
+	}
+	for i := range config.Heartbeats {
+		if config.Heartbeats[i].Name == name {
+			return config.Heartbeats[i].expanded()
+		}
+	}
+	for i := range config.Heartbeats {
+		heartbeat := &config.Heartbeats[i]
+		expanded, err := expandTemplate(name, mergeLabels(config.Labels, heartbeat.Labels))
+		if err == nil && heartbeat.templateErr == nil && expanded == heartbeat.Name {
+			return heartbeat, nil
+		}
+	}
+	return nil, fmt.Errorf("Heartbeat [%s] not found in config file", name)
+}
+
+func (config *Config) opsArgs(name string) (OpsArgs, error) {
+	heartbeat, err := config.heartbeat(name)
+	if err != nil {
+		return OpsArgs{}, err
+	}
+	args := OpsArgs{
+		ApiKey:        config.ApiKey,
+		Name:          heartbeat.Name,
+		Description:   heartbeat.Description,
+		Interval:      heartbeat.Interval,
+		IntervalUnit:  heartbeat.IntervalUnit,
+		Schedule:      heartbeat.Schedule,
+		TimeZone:      heartbeat.TimeZone,
+		Maintenance:   heartbeat.Maintenance,
+		StateDir:      config.StateDir,
+		LockFile:      heartbeat.LockFile,
+		LockURL:       heartbeat.LockURL,
+		Stagger:       heartbeat.Stagger,
+		Labels:        mergeLabels(config.Labels, heartbeat.Labels),
+		OwnerTeam:     heartbeat.OwnerTeam,
+		AlertMessage:  heartbeat.AlertMessage,
+		AlertTags:     heartbeat.AlertTags,
+		AlertPriority: heartbeat.AlertPriority,
+	}
+	if heartbeat.LeaseTime != "" {
+		args.LeaseTime, err = time.ParseDuration(heartbeat.LeaseTime)
+		if err != nil {
+			return OpsArgs{}, fmt.Errorf("[leaseTime] of heartbeat [%s] is not a valid duration: %s", heartbeat.Name, err)
+		}
+	}
+	if heartbeat.Jitter != "" {
+		args.Jitter, err = time.ParseDuration(heartbeat.Jitter)
+		if err != nil {
+			return OpsArgs{}, fmt.Errorf("[jitter] of heartbeat [%s] is not a valid duration: %s", heartbeat.Name, err)
+		}
+	}
+	for _, window := range heartbeat.Maintenance {
+		err = window.validate()
+		if err != nil {
+			return OpsArgs{}, err
+		}
+	}
+	if heartbeat.LoopInterval != "" {
+		args.LoopInterval, err = time.ParseDuration(heartbeat.LoopInterval)
+		if err != nil {
+			return OpsArgs{}, fmt.Errorf("[loopInterval] of heartbeat [%s] is not a valid duration: %s", heartbeat.Name, err)
+		}
+	}
+	return args, nil
+}
+
+//nameArg returns the name flag expanded the same way as the heartbeat names in the config file
+func nameArg(c *cli.Context) (string, error) {
+	name := c.GlobalString("name")
+	if name == "" {
+		return "", nil
+	}
+	if c.GlobalString("config") == "" {
+		return expandTemplate(name, nil)
+	}
+	config, err := loadConfig(c.GlobalString("config"))
+	if err != nil {
+		return "", err
+	}
+	if heartbeat, err := config.heartbeat(name); err == nil {
+		return heartbeat.Name, nil
+	}
+	return expandTemplate(name, config.Labels)
+}
+
+//apiKeyArg returns the apiKey flag or the apiKey from the config file, for commands that work on many heartbeats
+func apiKeyArg(c *cli.Context) (string, error) {
+	apiKey := c.GlobalString("apiKey")
+	if apiKey == "" && c.GlobalString("config") != "" {
+		config, err := loadConfig(c.GlobalString("config"))
+		if err != nil {
+			return "", err
+		}
+		apiKey = config.ApiKey
+	}
+	if apiKey == "" {
+		return "", fmt.Errorf("[apiKey] is mandatory")
+	}
+	return apiKey, nil
+}
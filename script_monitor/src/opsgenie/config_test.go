@@ -0,0 +1,136 @@
+package opsgenie
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/codegangsta/cli"
+)
+
+const testConfig = `{
+	"apiKey": "configKey",
+	"heartbeats": [
+		{"name": "first", "description": "first heartbeat", "interval": 5, "intervalUnit": "hours", "loopInterval": "30s"},
+		{"name": "second", "schedule": "0 9 * * *", "timeZone": "Europe/Amsterdam"}
+	]
+}`
+
+func writeConfigFile(t *testing.T, content string) string {
+	file, err := ioutil.TempFile("", "config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	return file.Name()
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := writeConfigFile(t, testConfig)
+	defer os.Remove(path)
+
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args, err := config.opsArgs("first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.ApiKey != "configKey" || args.Name != "first" || args.Description != "first heartbeat" || args.Interval != 5 || args.IntervalUnit != "hours" || args.LoopInterval != 30*time.Second {
+		t.Errorf("OpsArgs not correct [%+v]", args)
+	}
+	args, err = config.opsArgs("second")
+	if err != nil || args.Schedule != "0 9 * * *" || args.TimeZone != "Europe/Amsterdam" {
+		t.Errorf("OpsArgs not correct [%+v] [%v]", args, err)
+	}
+}
+
+func TestConfigHeartbeatSelection(t *testing.T) {
+	config := &Config{Heartbeats: []HeartbeatConfig{{Name: "only"}}}
+	if heartbeat, err := config.heartbeat(""); err != nil || heartbeat.Name != "only" {
+		t.Errorf("Single heartbeat not selected without name")
+	}
+	if _, err := config.heartbeat("other"); err == nil {
+		t.Errorf("Unknown heartbeat selected")
+	}
+	config.Heartbeats = append(config.Heartbeats, HeartbeatConfig{Name: "second"})
+	if _, err := config.heartbeat(""); err == nil {
+		t.Errorf("Heartbeat selected without name from multiple heartbeats")
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	path := writeConfigFile(t, `{"heartbeats": [{"name": "first", "loopInterval": "often"}]}`)
+	defer os.Remove(path)
+
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.opsArgs("first"); err == nil {
+		t.Errorf("Invalid loopInterval accepted")
+	}
+}
+
+func TestConfigJitter(t *testing.T) {
+	path := writeConfigFile(t, `{"heartbeats": [{"name": "first", "jitter": "10s", "stagger": true}, {"name": "second", "jitter": "sometimes"}]}`)
+	defer os.Remove(path)
+
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args, err := config.opsArgs("first")
+	if err != nil || args.Jitter != 10*time.Second || !args.Stagger {
+		t.Errorf("Jitter not read from config [%+v] [%v]", args, err)
+	}
+	if _, err := config.opsArgs("second"); err == nil {
+		t.Errorf("Invalid jitter accepted")
+	}
+}
+
+func TestConfigTemplatedName(t *testing.T) {
+	hostname, _ := os.Hostname()
+	path := writeConfigFile(t, `{"heartbeats": [{"name": "backup-{{.Hostname}}"}, {"name": "other"}]}`)
+	defer os.Remove(path)
+
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"backup-{{.Hostname}}", "backup-" + hostname} {
+		if heartbeat, err := config.heartbeat(name); err != nil || heartbeat.Name != "backup-"+hostname {
+			t.Errorf("Heartbeat [%s] not found by its name [%v]", name, err)
+		}
+	}
+
+	set, globalSet := createFlagSets("", "backup-{{.Hostname}}", "", "", 0, false)
+	globalSet.Set("config", path)
+	if name, err := nameArg(cli.NewContext(nil, set, globalSet)); err != nil || name != "backup-"+hostname {
+		t.Errorf("Name flag not expanded [%s] [%v]", name, err)
+	}
+}
+
+func TestConfigTemplateErrorOnlyForHeartbeat(t *testing.T) {
+	path := writeConfigFile(t, `{"heartbeats": [{"name": "first"}, {"name": "second", "description": "{{.Env.OPSGENIE_TEST_MISSING}}"}]}`)
+	defer os.Remove(path)
+
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.opsArgs("first"); err != nil {
+		t.Errorf("Heartbeat without template error not usable [%v]", err)
+	}
+	if _, err := config.opsArgs("second"); err == nil {
+		t.Errorf("Heartbeat with a missing env var accepted")
+	}
+	if err := config.templateErrors(); err == nil {
+		t.Errorf("Template error not reported for the whole config")
+	}
+}
@@ -0,0 +1,138 @@
+package opsgenie
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const redacted = "REDACTED"
+
+var debugHTTP bool
+var apiKeyJSON = regexp.MustCompile(`("apiKey"\s*:\s*)"[^"]*"`)
+var apiKeyQuery = regexp.MustCompile(`(apiKey=)[^&\s"]*`)
+
+//SetDebugHTTP logs every request and response with timings at debug level, credentials are always redacted
+func SetDebugHTTP(enabled bool) {
+	debugHTTP = enabled
+	if enabled && log.GetLevel() < log.DebugLevel {
+		log.SetLevel(log.DebugLevel)
+	}
+}
+
+//requestTiming holds the durations since the start of the request, a phase that did not happen stays zero
+type requestTiming struct {
+	start     time.Time
+	dns       time.Duration
+	connect   time.Duration
+	tls       time.Duration
+	firstByte time.Duration
+}
+
+func traceRequest(request *http.Request) (*http.Request, *requestTiming) {
+	timing := &requestTiming{start: time.Now()}
+	trace := &httptrace.ClientTrace{
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			timing.dns = time.Since(timing.start)
+		},
+		ConnectDone: func(string, string, error) {
+			timing.connect = time.Since(timing.start)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			timing.tls = time.Since(timing.start)
+		},
+		GotFirstResponseByte: func() {
+			timing.firstByte = time.Since(timing.start)
+		},
+	}
+	return request.WithContext(httptrace.WithClientTrace(request.Context(), trace)), timing
+}
+
+func logHTTPExchange(request *http.Request, response *http.Response, responseBody []byte, timing *requestTiming, err error) {
+	fields := log.Fields{
+		"method":         request.Method,
+		"url":            redactURL(request.URL),
+		"requestHeaders": redactHeaders(request.Header),
+		"dns":            timing.dns.String(),
+		"connect":        timing.connect.String(),
+		"tls":            timing.tls.String(),
+		"firstByte":      timing.firstByte.String(),
+		"total":          time.Since(timing.start).String(),
+	}
+	if request.GetBody != nil {
+		body, bodyErr := request.GetBody()
+		if bodyErr == nil {
+			content, _ := ioutil.ReadAll(body)
+			fields["requestBody"] = redactBody(string(content))
+		}
+	}
+	if err != nil {
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		log.WithFields(fields).Debugf("HTTP request failed: %s", err)
+		return
+	}
+	fields["status"] = response.StatusCode
+	fields["responseHeaders"] = redactHeaders(response.Header)
+	fields["responseBody"] = redactBody(string(responseBody))
+	log.WithFields(fields).Debug("HTTP request")
+}
+
+func redactURL(u *url.URL) string {
+	redactedURL := *u
+	query := redactedURL.Query()
+	if query.Get("apiKey") != "" {
+		query.Set("apiKey", redacted)
+		redactedURL.RawQuery = query.Encode()
+	}
+	return redactedURL.String()
+}
+
+//redactError redacts the url of a failed request, it contains the apiKey of GET requests
+func redactError(err error) error {
+	urlErr, ok := err.(*url.Error)
+	if !ok {
+		return err
+	}
+	u, parseErr := url.Parse(urlErr.URL)
+	if parseErr != nil {
+		return urlErr.Err
+	}
+	redactedErr := *urlErr
+	redactedErr.URL = redactURL(u)
+	return &redactedErr
+}
+
+func redactHeaders(headers http.Header) string {
+	lines := []string{}
+	for name, values := range headers {
+		for _, value := range values {
+			if strings.EqualFold(name, "Authorization") {
+				if strings.HasPrefix(value, "GenieKey ") {
+					value = "GenieKey " + redacted
+				} else {
+					value = redacted
+				}
+			}
+			lines = append(lines, name+": "+value)
+		}
+	}
+	return strings.Join(lines, ", ")
+}
+
+//redactText redacts an apiKey in a query string or JSON anywhere in the text
+func redactText(text string) string {
+	return redactBody(apiKeyQuery.ReplaceAllString(text, "${1}"+redacted))
+}
+
+func redactBody(body string) string {
+	return apiKeyJSON.ReplaceAllString(body, `$1"`+redacted+`"`)
+}
@@ -0,0 +1,93 @@
+package opsgenie
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+func TestRedactURL(t *testing.T) {
+	u, _ := url.Parse("https://api.opsgenie.com/v1/json/heartbeat?apiKey=secret&name=test")
+	redactedURL := redactURL(u)
+	if strings.Contains(redactedURL, "secret") || !strings.Contains(redactedURL, "apiKey=REDACTED") || !strings.Contains(redactedURL, "name=test") {
+		t.Errorf("Url not redacted [%s]", redactedURL)
+	}
+	if u.RawQuery != "apiKey=secret&name=test" {
+		t.Errorf("Original url changed [%s]", u)
+	}
+}
+
+func TestRedactHeaders(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Authorization", "GenieKey secret")
+	headers.Set("Content-Type", "application/json")
+	redactedHeaders := redactHeaders(headers)
+	if strings.Contains(redactedHeaders, "secret") || !strings.Contains(redactedHeaders, "Authorization: GenieKey REDACTED") || !strings.Contains(redactedHeaders, "Content-Type: application/json") {
+		t.Errorf("Headers not redacted [%s]", redactedHeaders)
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	body := redactBody(`{"apiKey": "secret","name":"test"}`)
+	if body != `{"apiKey": "REDACTED","name":"test"}` {
+		t.Errorf("Body not redacted [%s]", body)
+	}
+}
+
+func TestDebugHTTPRequest(t *testing.T) {
+	server, paths := startTestServer()
+	defer stopTestServer(server)
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	defer log.SetOutput(os.Stderr)
+	level := log.GetLevel()
+	defer log.SetLevel(level)
+	defer func() { debugHTTP = false }()
+	SetDebugHTTP(true)
+
+	args := testargs
+	args.ApiKey = "secretApiKey"
+	err := SendHeartbeat(args)
+	if err != nil || len(*paths) != 1 {
+		t.Errorf("Heartbeat not sent with debugHttp [%v]", err)
+	}
+	output := out.String()
+	for _, expected := range []string{"HTTP request", "method=POST", "url=\"" + server.URL + "/v1/json/heartbeat/send", "dns=", "connect=", "firstByte=", "total=", "status=200", "REDACTED"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Debug log does not contain [%s] [%s]", expected, output)
+		}
+	}
+	if strings.Contains(output, "secretApiKey") {
+		t.Errorf("Debug log contains the api key [%s]", output)
+	}
+}
+
+func TestDebugHTTPFailedRequestRedacted(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	SetAPIURL(server.URL)
+	defer SetAPIURL("https://api.opsgenie.com")
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	defer log.SetOutput(os.Stderr)
+	level := log.GetLevel()
+	defer log.SetLevel(level)
+	defer func() { debugHTTP = false }()
+	SetDebugHTTP(true)
+
+	args := testargs
+	args.ApiKey = "secretApiKey"
+	_, err := getHeartbeat(args, requestFields("get", args.Name))
+	if err == nil || strings.Contains(err.Error(), "secretApiKey") {
+		t.Errorf("Error of failed request not redacted [%v]", err)
+	}
+	if !strings.Contains(out.String(), "HTTP request failed") || strings.Contains(out.String(), "secretApiKey") {
+		t.Errorf("Log of failed request not redacted [%s]", out.String())
+	}
+}
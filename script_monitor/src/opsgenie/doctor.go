@@ -0,0 +1,184 @@
+package opsgenie
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+//doctorCheck is the result of one diagnostic step, Hint tells how to fix a failed step
+type doctorCheck struct {
+	Name    string
+	Passed  bool
+	Skipped bool
+	Detail  string
+	Hint    string
+}
+
+//runDoctor checks step by step whether the heartbeat in args can be sent and reports whether all steps passed
+func runDoctor(args OpsArgs, w io.Writer) bool {
+	api, err := url.Parse(apiURL)
+	if err != nil {
+		printCheck(w, doctorCheck{Name: "api url", Detail: err.Error(), Hint: "the api url should look like https://api.opsgenie.com"})
+		return false
+	}
+	address := api.Host
+	if api.Port() == "" {
+		address = net.JoinHostPort(api.Hostname(), map[string]string{"http": "80", "https": "443"}[api.Scheme])
+	}
+	request, _ := http.NewRequest("GET", apiURL, nil)
+	proxy, proxyErr := proxyForRequest(request)
+
+	checks := []doctorCheck{checkProxy(proxy, proxyErr)}
+	if proxy == nil {
+		checks = append(checks, checkDNS(api.Hostname()), checkTCP(address))
+	} else {
+		skipped := "skipped, requests go through proxy " + proxy.Host
+		checks = append(checks, doctorCheck{Name: "dns", Skipped: true, Detail: skipped}, doctorCheck{Name: "tcp", Skipped: true, Detail: skipped})
+	}
+	checks = append(checks, checkTLS(api))
+	apiKeyCheck, heartbeat := checkAPIKey(args)
+	checks = append(checks, apiKeyCheck)
+	if apiKeyCheck.Passed {
+		checks = append(checks, checkHeartbeat(args, heartbeat))
+	} else {
+		checks = append(checks, doctorCheck{Name: "heartbeat", Skipped: true, Detail: "skipped, the api key is not valid"})
+	}
+
+	passed := true
+	for _, check := range checks {
+		printCheck(w, check)
+		passed = passed && (check.Passed || check.Skipped)
+	}
+	return passed
+}
+
+func printCheck(w io.Writer, check doctorCheck) {
+	status := "FAIL"
+	if check.Skipped {
+		status = "SKIP"
+	} else if check.Passed {
+		status = "PASS"
+	}
+	fmt.Fprintf(w, "[%s] %-10s %s\n", status, check.Name, check.Detail)
+	if !check.Passed && !check.Skipped && check.Hint != "" {
+		fmt.Fprintf(w, "       %-10s %s\n", "hint:", check.Hint)
+	}
+}
+
+func checkProxy(proxy *url.URL, err error) doctorCheck {
+	check := doctorCheck{Name: "proxy", Hint: "check the proxy flag, the proxy in the config file or the HTTPS_PROXY environment variable"}
+	if err != nil {
+		check.Detail = err.Error()
+		return check
+	}
+	if proxy == nil {
+		check.Passed, check.Detail = true, "no proxy configured, connecting directly"
+		return check
+	}
+	address := proxy.Host
+	if proxy.Port() == "" {
+		address = net.JoinHostPort(proxy.Hostname(), map[string]string{"http": "80", "https": "443", "socks5": "1080"}[proxy.Scheme])
+	}
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		check.Detail = fmt.Sprintf("proxy %s is not reachable: %s", address, err)
+		return check
+	}
+	conn.Close()
+	check.Passed, check.Detail = true, fmt.Sprintf("proxy %s is reachable", address)
+	return check
+}
+
+func checkDNS(host string) doctorCheck {
+	check := doctorCheck{Name: "dns", Hint: "check /etc/resolv.conf and whether this host may resolve external names, otherwise configure a proxy"}
+	addresses, err := net.LookupHost(host)
+	if err != nil {
+		check.Detail = err.Error()
+		return check
+	}
+	check.Passed, check.Detail = true, fmt.Sprintf("%s resolves to %s", host, strings.Join(addresses, ", "))
+	return check
+}
+
+func checkTCP(address string) doctorCheck {
+	check := doctorCheck{Name: "tcp", Hint: "check firewalls for outgoing connections to " + address + ", otherwise configure a proxy"}
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		check.Detail = err.Error()
+		return check
+	}
+	conn.Close()
+	check.Passed, check.Detail = true, fmt.Sprintf("connected to %s in %s", address, time.Since(start))
+	return check
+}
+
+//checkTLS shows the certificate of the api, so a broken chain or an intercepting proxy is visible
+func checkTLS(api *url.URL) doctorCheck {
+	check := doctorCheck{Name: "tls", Hint: "check the system CA certificates and whether a proxy intercepts TLS"}
+	if api.Scheme != "https" {
+		check.Skipped, check.Detail = true, "skipped, the api url is not https"
+		return check
+	}
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{Proxy: proxyForRequest, TLSClientConfig: &tls.Config{}},
+	}
+	resp, err := client.Get(api.String())
+	if err != nil {
+		check.Detail = err.Error()
+		return check
+	}
+	resp.Body.Close()
+	if resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+		check.Detail = "no certificate received"
+		return check
+	}
+	certificate := resp.TLS.PeerCertificates[0]
+	check.Passed = true
+	check.Detail = fmt.Sprintf("certificate for %s issued by %s, valid until %s", certificate.Subject.CommonName, certificate.Issuer.CommonName, certificate.NotAfter.Format("2006-01-02"))
+	return check
+}
+
+//checkAPIKey validates the key with a get of the configured heartbeat, so a key that can't list heartbeats is still accepted.
+//It returns the heartbeat, which is nil when it doesn't exist.
+func checkAPIKey(args OpsArgs) (doctorCheck, *Heartbeat) {
+	check := doctorCheck{Name: "api key", Hint: "check the apiKey flag or the config file, the key should belong to an API integration with access to heartbeats"}
+	heartbeat, err := getHeartbeat(args, requestFields("get", args.Name))
+	if err != nil {
+		check.Detail = err.Error()
+		return check, nil
+	}
+	check.Passed, check.Detail = true, "api key is accepted"
+	return check, heartbeat
+}
+
+func checkHeartbeat(args OpsArgs, heartbeat *Heartbeat) doctorCheck {
+	check := doctorCheck{Name: "heartbeat", Hint: "run start to add and enable the heartbeat [" + args.Name + "]"}
+	if heartbeat == nil {
+		check.Detail = fmt.Sprintf("heartbeat [%s] does not exist", args.Name)
+		return check
+	}
+	if !heartbeat.Enabled {
+		check.Detail = fmt.Sprintf("heartbeat [%s] exists but is disabled", args.Name)
+		return check
+	}
+	check.Passed, check.Detail = true, fmt.Sprintf("heartbeat [%s] exists and is enabled", args.Name)
+	if heartbeat.LastHeartbeat > 0 {
+		check.Detail += ", last heartbeat at " + time.Unix(0, heartbeat.LastHeartbeat*int64(time.Millisecond)).Format(time.RFC3339)
+	}
+	return check
+}
+
+func doctor(args OpsArgs) {
+	if !runDoctor(args, os.Stdout) {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,66 @@
+package opsgenie
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRunDoctor(t *testing.T) {
+	server, _ := startTestServer()
+	defer stopTestServer(server)
+
+	var out bytes.Buffer
+	passed := runDoctor(testargs, &out)
+	output := out.String()
+	if passed {
+		t.Errorf("Doctor passed for a disabled heartbeat [%s]", output)
+	}
+	for _, expected := range []string{"[PASS] proxy", "[PASS] dns", "[PASS] tcp", "[SKIP] tls", "[PASS] api key", "[FAIL] heartbeat", "exists but is disabled", "hint:"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Doctor output does not contain [%s] [%s]", expected, output)
+		}
+	}
+}
+
+func TestCheckAPIKeyWithoutListAccess(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/json/heartbeat" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"code": 3, "error": "not allowed"}`))
+			return
+		}
+		w.Write([]byte(`{"name": "test", "enabled": true}`))
+	}))
+	defer stopTestServer(server)
+	SetAPIURL(server.URL)
+
+	check, heartbeat := checkAPIKey(testargs)
+	if !check.Passed || heartbeat == nil || !heartbeat.Enabled {
+		t.Errorf("Api key that can get the heartbeat is not valid [%+v] [%+v]", check, heartbeat)
+	}
+}
+
+func TestCheckTLSFailsForUntrustedCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	api, _ := url.Parse(server.URL)
+	check := checkTLS(api)
+	if check.Passed || check.Skipped || check.Hint == "" {
+		t.Errorf("TLS check passed for an untrusted certificate [%+v]", check)
+	}
+}
+
+func TestCheckTCPFailsForClosedPort(t *testing.T) {
+	server, _ := startTestServer()
+	stopTestServer(server)
+
+	check := checkTCP(strings.TrimPrefix(server.URL, "http://"))
+	if check.Passed || check.Hint == "" {
+		t.Errorf("TCP check passed for a closed server [%+v]", check)
+	}
+}
@@ -0,0 +1,274 @@
+package opsgenie
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/codegangsta/cli"
+)
+
+const commandMandatory = "A command is mandatory, put it after --"
+
+//killGrace is the time a command and the processes it started get to stop after SIGTERM before they are killed
+var killGrace = 10 * time.Second
+
+var intervalUnits = map[string]time.Duration{"minutes": time.Minute, "hours": time.Hour, "days": 24 * time.Hour}
+
+//toInterval converts a duration to the largest interval unit that fits it exactly, otherwise to minutes rounded up
+func toInterval(d time.Duration) (int, string) {
+	for _, unit := range []string{"days", "hours"} {
+		if d%intervalUnits[unit] == 0 {
+			return int(d / intervalUnits[unit]), unit
+		}
+	}
+	return int((d + time.Minute - 1) / time.Minute), "minutes"
+}
+
+//execArgs returns the started and finished heartbeats, the finished heartbeat allows maxRuntime more than the interval
+func execArgs(args OpsArgs, maxRuntime time.Duration) (OpsArgs, OpsArgs) {
+	started := args
+	started.Name = args.Name + "-started"
+	finished := args
+	finished.Name = args.Name + "-finished"
+	unit, ok := intervalUnits[args.IntervalUnit]
+	if ok && args.Interval > 0 && maxRuntime > 0 {
+		finished.Interval, finished.IntervalUnit = toInterval(time.Duration(args.Interval)*unit + maxRuntime)
+	}
+	return started, finished
+}
+
+var execFlags = []cli.Flag{
+	cli.DurationFlag{
+		Name:  "maxRuntime, m",
+		Value: 0,
+		Usage: "Stop the command after this duration, 0 means no limit",
+	},
+	cli.BoolFlag{
+		Name:  "alert",
+		Usage: "Create an alert with the exit code and the last output when the command fails",
+	},
+	cli.BoolFlag{
+		Name:  "expire",
+		Usage: "Expire the finished heartbeat right away when the command fails",
+	},
+	cli.IntFlag{
+		Name:  "outputSize",
+		Value: 4,
+		Usage: "KB of the last stdout and stderr output to add to the alert",
+	},
+	cli.StringSliceFlag{
+		Name:  "priority",
+		Value: &cli.StringSlice{},
+		Usage: "Alert priority per exit code like 2=P2, * is used for the other exit codes, can be repeated (default *=P3)",
+	},
+}
+
+type execOptions struct {
+	MaxRuntime time.Duration
+	Alert      bool
+	Expire     bool
+	OutputSize int
+	Priorities map[string]string
+}
+
+func extractExecOptions(c *cli.Context) execOptions {
+	priorities, err := parsePriorities(c.StringSlice("priority"))
+	if err != nil {
+		logAndExit(err.Error())
+	}
+	return execOptions{
+		MaxRuntime: c.Duration("maxRuntime"),
+		Alert:      c.Bool("alert"),
+		Expire:     c.Bool("expire"),
+		OutputSize: c.Int("outputSize"),
+		Priorities: priorities,
+	}
+}
+
+//execHeartbeat pings the started heartbeat and adds the finished heartbeat, so a command that hangs or fails on its first run
+//also alerts. It runs the command and pings the finished heartbeat when the command succeeded within the max runtime, otherwise an alert is created and the finished heartbeat expired when asked for. It returns the exit code of the command.
+func execHeartbeat(args OpsArgs, options execOptions, command []string) int {
+	if len(command) == 0 {
+		logAndExit(commandMandatory)
+		return 1
+	}
+	started, finished := execArgs(args, options.MaxRuntime)
+	fields := requestFields("exec", args.Name)
+	if err := startHeartbeatAndSend(started); err != nil {
+		log.WithFields(fields).Errorf("Could not ping heartbeat [%s], the start of the job is not monitored: %s", started.Name, err)
+	}
+	if err := startHeartbeat(finished); err != nil {
+		log.WithFields(fields).Errorf("Could not add or update heartbeat [%s], the job may fail without an alert: %s", finished.Name, err)
+	}
+	output := &tailBuffer{size: options.OutputSize * 1024}
+	stdout, stderr := io.Writer(os.Stdout), io.Writer(os.Stderr)
+	if options.Alert {
+		stdout, stderr = io.MultiWriter(os.Stdout, output), io.MultiWriter(os.Stderr, output)
+	}
+	start := time.Now()
+	code, err := runCommandOutput(command, options.MaxRuntime, stdout, stderr)
+	duration := time.Since(start)
+	fields["exitCode"] = code
+	fields["duration"] = duration.String()
+	if err != nil || code != 0 {
+		if err != nil {
+			fields["error"] = err.Error()
+		}
+		log.WithFields(fields).Errorf("Command failed, not sending heartbeat [%s]", finished.Name)
+		if options.Alert || options.Expire {
+			alert := execAlert(args, finished, options, command, code, err, duration, output.String())
+			if createAlert(args, alert) == nil {
+				if err := recordAlert(finished, alert.Alias); err != nil {
+					log.WithFields(fields).Errorf("Could not record alert [%s], the next ping does not close it: %s", alert.Alias, err)
+				}
+			}
+		}
+		return code
+	}
+	log.WithFields(fields).Info("Command succeeded")
+	sendHeartbeat(finished)
+	if (options.Alert || options.Expire) && finished.StateDir == "" {
+		//without stateDir it is unknown whether a previous run created an alert, so the alert is closed in case it is open
+		if err := closeAlert(args, execAlias(args)); err != nil {
+			log.WithFields(fields).Infof("No alert [%s] closed, it is probably not open: %s", execAlias(args), err)
+		}
+	}
+	return code
+}
+
+//execAlias is the alias of the alert of a failed run, shared by -alert and -expire
+func execAlias(args OpsArgs) string {
+	return args.Name + "-failed"
+}
+
+//execAlert combines -alert and -expire into one alert, so a failed run creates a single alert. With -expire it is the
+//alert of the expired finished heartbeat, with -alert it has the output of the command.
+func execAlert(args OpsArgs, finished OpsArgs, options execOptions, command []string, code int, err error, duration time.Duration, output string) Alert {
+	var alert Alert
+	if options.Expire {
+		priority := ""
+		if len(options.Priorities) > 0 {
+			priority = priorityFor(options.Priorities, code)
+		}
+		alert = expiredAlert(finished, fmt.Sprintf("Command [%s] failed with exit code %d", strings.Join(command, " "), code), priority)
+	}
+	if options.Alert {
+		failure := failureAlert(args, options, command, code, err, duration, output)
+		if options.Expire {
+			failure.Priority, failure.Tags, failure.Teams = alert.Priority, alert.Tags, alert.Teams
+		}
+		alert = failure
+	}
+	alert.Alias = execAlias(args)
+	return alert
+}
+
+//failureAlert uses the heartbeat name as alias so repeated failures are deduplicated into one alert
+func failureAlert(args OpsArgs, options execOptions, command []string, code int, err error, duration time.Duration, output string) Alert {
+	host, _ := os.Hostname()
+	details := map[string]string{
+		"command":  strings.Join(command, " "),
+		"exitCode": strconv.Itoa(code),
+		"duration": duration.String(),
+		"host":     host,
+	}
+	if err != nil {
+		details["error"] = err.Error()
+	}
+	return Alert{
+		Message:     fmt.Sprintf("Job [%s] failed with exit code %d on %s", args.Name, code, host),
+		Alias:       execAlias(args),
+		Description: output,
+		Source:      host,
+		Priority:    priorityFor(options.Priorities, code),
+		Details:     details,
+	}
+}
+
+//runCommand runs the command with the standard streams of this process in its own process group, signals are forwarded to
+//the group and after maxRuntime the group is stopped and 124 is returned
+func runCommand(command []string, maxRuntime time.Duration) (int, error) {
+	return runCommandOutput(command, maxRuntime, os.Stdout, os.Stderr)
+}
+
+func runCommandOutput(command []string, maxRuntime time.Duration, stdout io.Writer, stderr io.Writer) (int, error) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+	return runCommandGroup(command, maxRuntime, stdout, stderr, signals)
+}
+
+//runProbe runs a probe without forwarding signals, those are for the command the probe checks
+func runProbe(command []string, timeout time.Duration) (int, error) {
+	return runCommandGroup(command, timeout, os.Stdout, os.Stderr, nil)
+}
+
+func runCommandGroup(command []string, maxRuntime time.Duration, stdout io.Writer, stderr io.Writer, signals <-chan os.Signal) (int, error) {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, stdout, stderr
+	newProcessGroup(cmd)
+	err := cmd.Start()
+	if err != nil {
+		return 127, err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	var timeout <-chan time.Time
+	if maxRuntime > 0 {
+		timer := time.NewTimer(maxRuntime)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	for {
+		select {
+		case sig := <-signals:
+			signalCommand(cmd, sig)
+		case err := <-done:
+			return exitCode(err)
+		case <-timeout:
+			stopCommand(cmd, done)
+			return 124, fmt.Errorf("Command exceeded the max runtime of %s", maxRuntime)
+		}
+	}
+}
+
+//stopCommand stops the process group, so a child that keeps the output open can't block the command from finishing
+func stopCommand(cmd *exec.Cmd, done chan error) {
+	terminateCommand(cmd)
+	select {
+	case <-done:
+	case <-time.After(killGrace):
+		killCommand(cmd)
+		<-done
+	}
+}
+
+//exitCode returns 128 plus the signal number for a command that was killed by a signal, like a shell does
+func exitCode(err error) (int, error) {
+	if err == nil {
+		return 0, nil
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal()), nil
+		}
+		return exitErr.ExitCode(), nil
+	}
+	return 1, err
+}
+
+//isTerminal reports whether the file is a character device like a terminal
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,189 @@
+package opsgenie
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+func TestToInterval(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		interval int
+		unit     string
+	}{
+		{48 * time.Hour, 2, "days"},
+		{30 * time.Hour, 30, "hours"},
+		{90 * time.Minute, 90, "minutes"},
+		{90*time.Minute + time.Second, 91, "minutes"},
+	}
+	for _, test := range tests {
+		interval, unit := toInterval(test.duration)
+		if interval != test.interval || unit != test.unit {
+			t.Errorf("Duration [%s] is [%d %s] but should be [%d %s]", test.duration, interval, unit, test.interval, test.unit)
+		}
+	}
+}
+
+func TestExecArgs(t *testing.T) {
+	started, finished := execArgs(OpsArgs{Name: "etl", Interval: 1, IntervalUnit: "days"}, 6*time.Hour)
+	if started.Name != "etl-started" || started.Interval != 1 || started.IntervalUnit != "days" {
+		t.Errorf("Started heartbeat not correct [%+v]", started)
+	}
+	if finished.Name != "etl-finished" || finished.Interval != 30 || finished.IntervalUnit != "hours" {
+		t.Errorf("Finished heartbeat not correct [%+v]", finished)
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	if code, err := runCommand([]string{"sh", "-c", "exit 3"}, 0); code != 3 || err != nil {
+		t.Errorf("Exit code is [%d] [%v]", code, err)
+	}
+	if code, err := runCommand([]string{"/nonexistent/command"}, 0); code != 127 || err == nil {
+		t.Errorf("Missing command gave [%d] [%v]", code, err)
+	}
+	start := time.Now()
+	if code, err := runCommand([]string{"sleep", "5"}, 50*time.Millisecond); code != 124 || err == nil || time.Since(start) > 2*time.Second {
+		t.Errorf("Command not stopped after max runtime [%d] [%v]", code, err)
+	}
+}
+
+func TestRunCommandStopsProcessGroup(t *testing.T) {
+	var output bytes.Buffer
+	start := time.Now()
+	code, err := runCommandOutput([]string{"sh", "-c", "sleep 5; true"}, 50*time.Millisecond, &output, &output)
+	if code != 124 || err == nil || time.Since(start) > 2*time.Second {
+		t.Errorf("Child of the command not stopped after max runtime [%d] [%v] [%s]", code, err, time.Since(start))
+	}
+}
+
+func TestExecHeartbeat(t *testing.T) {
+	server, paths := startTestServer()
+	defer stopTestServer(server)
+
+	if code := execHeartbeat(testargs, execOptions{}, []string{"true"}); code != 0 || len(*paths) != 6 {
+		t.Errorf("Successful command gave [%d] with requests [%v]", code, *paths)
+	}
+	*paths = nil
+	if code := execHeartbeat(testargs, execOptions{}, []string{"false"}); code != 1 || len(*paths) != 5 || countPaths(*paths, "/v1/json/heartbeat/send") != 1 {
+		t.Errorf("Failed command gave [%d] with requests [%v]", code, *paths)
+	}
+}
+
+func TestExecHeartbeatLogsStartFailure(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	SetAPIURL(server.URL)
+	defer SetAPIURL("https://api.opsgenie.com")
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	defer log.SetOutput(os.Stderr)
+
+	if code := execHeartbeat(testargs, execOptions{}, []string{"true"}); code != 0 {
+		t.Errorf("Command did not run without OpsGenie [%d]", code)
+	}
+	for _, name := range []string{testargs.Name + "-started", testargs.Name + "-finished"} {
+		if !strings.Contains(out.String(), "level=error") || !strings.Contains(out.String(), "heartbeat ["+name+"]") {
+			t.Errorf("Failure of heartbeat [%s] not logged [%s]", name, out.String())
+		}
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	file, err := ioutil.TempFile("", "stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	if isTerminal(file) {
+		t.Error("Regular file is a terminal")
+	}
+}
+
+func TestExecHeartbeatAlert(t *testing.T) {
+	server, paths := startTestServer()
+	defer stopTestServer(server)
+	options := execOptions{Alert: true, OutputSize: 1, Priorities: map[string]string{"3": "P1"}}
+
+	if code := execHeartbeat(testargs, options, []string{"sh", "-c", "echo broken; exit 3"}); code != 3 || countPaths(*paths, "/v1/json/alert") != 1 {
+		t.Errorf("Failed command gave [%d] with requests [%v]", code, *paths)
+	}
+	*paths = nil
+	if code := execHeartbeat(testargs, options, []string{"true"}); code != 0 || countPaths(*paths, "/v1/json/alert") != 0 {
+		t.Errorf("Successful command gave [%d] with requests [%v]", code, *paths)
+	}
+}
+
+func TestFailureAlert(t *testing.T) {
+	options := execOptions{Priorities: map[string]string{"3": "P1", "*": "P4"}}
+	alert := failureAlert(testargs, options, []string{"etl", "--full"}, 3, nil, time.Minute, "broken")
+	if alert.Alias != testargs.Name+"-failed" || alert.Priority != "P1" || alert.Description != "broken" {
+		t.Errorf("Alert not correct [%+v]", alert)
+	}
+	if alert.Details["exitCode"] != "3" || alert.Details["command"] != "etl --full" || alert.Details["duration"] != "1m0s" {
+		t.Errorf("Alert details not correct [%v]", alert.Details)
+	}
+	if alert = failureAlert(testargs, options, []string{"etl"}, 1, nil, time.Minute, ""); alert.Priority != "P4" {
+		t.Errorf("Default priority not used [%+v]", alert)
+	}
+}
+
+func TestExecAlert(t *testing.T) {
+	_, finished := execArgs(testargs, time.Hour)
+	options := execOptions{Alert: true, Expire: true}
+	alert := execAlert(testargs, finished, options, []string{"etl"}, 3, nil, time.Minute, "broken")
+	if alert.Alias != testargs.Name+"-failed" || alert.Description != "broken" || !strings.Contains(strings.Join(alert.Tags, ","), "Expired") {
+		t.Errorf("Alert and expire not combined [%+v]", alert)
+	}
+	alert = execAlert(testargs, finished, execOptions{Expire: true}, []string{"etl"}, 3, nil, time.Minute, "")
+	if alert.Alias != testargs.Name+"-failed" || alert.Details["heartbeat"] != finished.Name {
+		t.Errorf("Expire alert does not use the shared alias [%+v]", alert)
+	}
+}
+
+func TestExecHeartbeatAlertClosed(t *testing.T) {
+	server, paths := startTestServer()
+	defer stopTestServer(server)
+	options := execOptions{Alert: true, Expire: true, OutputSize: 1}
+
+	execHeartbeat(testargs, options, []string{"false"})
+	if countPaths(*paths, "/v1/json/alert") != 1 {
+		t.Errorf("Failed command with alert and expire should create one alert [%v]", *paths)
+	}
+	*paths = nil
+	execHeartbeat(testargs, options, []string{"true"})
+	if countPaths(*paths, "/v1/json/alert/close") != 1 {
+		t.Errorf("Successful command should close the alert [%v]", *paths)
+	}
+}
+
+func TestExecAlertClosedByPing(t *testing.T) {
+	server, paths := startTestServer()
+	defer stopTestServer(server)
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	args := testargs
+	args.StateDir = dir
+
+	execHeartbeat(args, execOptions{Expire: true}, []string{"false"})
+	_, finished := execArgs(args, 0)
+	*paths = nil
+	if err := SendHeartbeat(finished); err != nil || countPaths(*paths, "/v1/json/alert/close") != 1 {
+		t.Errorf("Ping should close the recorded alert [%v] [%v]", *paths, err)
+	}
+	*paths = nil
+	if err := SendHeartbeat(finished); err != nil || countPaths(*paths, "/v1/json/alert/close") != 0 {
+		t.Errorf("Alert closed twice [%v] [%v]", *paths, err)
+	}
+}
@@ -0,0 +1,39 @@
+package opsgenie
+
+import (
+	"fmt"
+	"os"
+)
+
+//expireHeartbeat creates the alert OpsGenie creates for an expired heartbeat right away, the v1 heartbeat API can not
+//expire a heartbeat itself. The alias makes repeated failures end up in the same alert. The alert settings of the
+//heartbeat are used when they are set, an empty priority falls back to the alert priority of the heartbeat.
+func expireHeartbeat(args OpsArgs, reason string, priority string) error {
+	return createAlert(args, expiredAlert(args, reason, priority))
+}
+
+func expiredAlert(args OpsArgs, reason string, priority string) Alert {
+	host, _ := os.Hostname()
+	alert := Alert{
+		Message:     fmt.Sprintf("Heartbeat [%s] expired", args.Name),
+		Alias:       args.Name + "-expired",
+		Description: reason,
+		Source:      host,
+		Priority:    priority,
+		Tags:        append([]string{"OpsGenie", "Heartbeat", "Expired"}, args.AlertTags...),
+		Details:     map[string]string{"heartbeat": args.Name, "host": host},
+	}
+	if args.AlertMessage != "" {
+		alert.Message = args.AlertMessage
+	}
+	if alert.Priority == "" {
+		alert.Priority = args.AlertPriority
+	}
+	if alert.Priority == "" {
+		alert.Priority = defaultPriority
+	}
+	if args.OwnerTeam != "" {
+		alert.Teams = []string{args.OwnerTeam}
+	}
+	return alert
+}
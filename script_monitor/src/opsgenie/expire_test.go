@@ -0,0 +1,60 @@
+package opsgenie
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpireHeartbeat(t *testing.T) {
+	var content map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &content)
+		w.Write([]byte("{}"))
+	}))
+	defer stopTestServer(server)
+	SetAPIURL(server.URL)
+
+	if err := expireHeartbeat(testargs, "disk full", "P2"); err != nil {
+		t.Fatal(err)
+	}
+	if content["alias"] != testargs.Name+"-expired" || content["description"] != "disk full" || content["priority"] != "P2" {
+		t.Errorf("Expire alert not correct [%v]", content)
+	}
+
+	args := testargs
+	args.AlertMessage, args.AlertPriority, args.AlertTags, args.OwnerTeam = "Backup missing", "P1", []string{"db"}, "ops"
+	if err := expireHeartbeat(args, "disk full", ""); err != nil {
+		t.Fatal(err)
+	}
+	tags := fmt.Sprint(content["tags"])
+	if content["message"] != "Backup missing" || content["priority"] != "P1" || !strings.Contains(tags, "db") || fmt.Sprint(content["teams"]) != "[ops]" {
+		t.Errorf("Expire alert does not use the heartbeat settings [%v]", content)
+	}
+	if err := expireHeartbeat(args, "disk full", "P4"); err != nil || content["priority"] != "P4" {
+		t.Errorf("Priority argument not used [%v] [%v]", content, err)
+	}
+}
+
+func TestExecHeartbeatExpire(t *testing.T) {
+	server, paths := startTestServer()
+	defer stopTestServer(server)
+
+	execHeartbeat(testargs, execOptions{Expire: true}, []string{"false"})
+	if countPaths(*paths, "/v1/json/alert") != 1 {
+		t.Errorf("Failed command should expire the heartbeat [%v]", *paths)
+	}
+	*paths = nil
+	args := testargs
+	args.LoopInterval = time.Hour
+	superviseCommand(args, "", true, []string{"false"})
+	if countPaths(*paths, "/v1/json/alert") != 1 {
+		t.Errorf("Crashed command should expire the heartbeat [%v]", *paths)
+	}
+}
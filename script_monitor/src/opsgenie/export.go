@@ -0,0 +1,98 @@
+package opsgenie
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/codegangsta/cli"
+)
+
+//exportConfig converts the heartbeats matching one of the glob filters, or all without filters, to the config file format sorted by name.
+//Names, descriptions and alert messages are escaped so they are not expanded as templates when the config is loaded.
+func exportConfig(heartbeats []Heartbeat, filters []string) (*Config, error) {
+	config := &Config{Heartbeats: []HeartbeatConfig{}}
+	for _, heartbeat := range heartbeats {
+		matched, err := matchesAny(heartbeat.Name, filters)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		heartbeatConfig := HeartbeatConfig{
+			Name:          escapeTemplate(heartbeat.Name),
+			Description:   escapeTemplate(heartbeat.Description),
+			Interval:      heartbeat.Interval,
+			IntervalUnit:  heartbeat.IntervalUnit,
+			OwnerTeam:     heartbeat.OwnerTeam,
+			AlertMessage:  escapeTemplate(heartbeat.AlertMessage),
+			AlertTags:     heartbeat.AlertTags,
+			AlertPriority: heartbeat.AlertPriority,
+		}
+		if !heartbeat.Enabled {
+			disabled := false
+			heartbeatConfig.Enabled = &disabled
+		}
+		config.Heartbeats = append(config.Heartbeats, heartbeatConfig)
+	}
+	sort.Sort(configByName(config.Heartbeats))
+	return config, nil
+}
+
+func matchesAny(name string, patterns []string) (bool, error) {
+	if len(patterns) == 0 {
+		return true, nil
+	}
+	for _, pattern := range patterns {
+		matched, err := path.Match(pattern, name)
+		if err != nil {
+			return false, fmt.Errorf("Pattern [%s] is not valid: %s", pattern, err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type configByName []HeartbeatConfig
+
+func (h configByName) Len() int           { return len(h) }
+func (h configByName) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h configByName) Less(i, j int) bool { return h[i].Name < h[j].Name }
+
+func exportHeartbeats(c *cli.Context) {
+	apiKey, err := apiKeyArg(c)
+	if err != nil {
+		logAndExit(err.Error())
+		return
+	}
+	heartbeats, err := listHeartbeats(apiKey)
+	if err != nil {
+		logAndExit(err.Error())
+		return
+	}
+	config, err := exportConfig(heartbeats, c.StringSlice("filter"))
+	if err != nil {
+		logAndExit(err.Error())
+		return
+	}
+	content, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		logAndExit(err.Error())
+		return
+	}
+	content = append(content, '\n')
+	if c.String("output") == "" {
+		os.Stdout.Write(content)
+		return
+	}
+	err = ioutil.WriteFile(c.String("output"), content, 0644)
+	if err != nil {
+		logAndExit(err.Error())
+	}
+}
@@ -0,0 +1,50 @@
+package opsgenie
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExportConfig(t *testing.T) {
+	heartbeats := []Heartbeat{
+		{Name: "web-02", Interval: 10, IntervalUnit: "minutes", Enabled: true},
+		{Name: "db-backup", Description: "nightly", Interval: 1, IntervalUnit: "days", Enabled: false},
+		{Name: "web-01", Interval: 10, IntervalUnit: "minutes", Enabled: true},
+	}
+	disabled := false
+
+	config, err := exportConfig(heartbeats, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []HeartbeatConfig{
+		{Name: "db-backup", Description: "nightly", Interval: 1, IntervalUnit: "days", Enabled: &disabled},
+		{Name: "web-01", Interval: 10, IntervalUnit: "minutes"},
+		{Name: "web-02", Interval: 10, IntervalUnit: "minutes"},
+	}
+	if !reflect.DeepEqual(config.Heartbeats, expected) {
+		t.Errorf("Exported heartbeats are [%+v] but should be [%+v]", config.Heartbeats, expected)
+	}
+
+	config, err = exportConfig(heartbeats, []string{"web-*"})
+	if err != nil || len(config.Heartbeats) != 2 || config.Heartbeats[0].Name != "web-01" {
+		t.Errorf("Filtered heartbeats are [%+v] [%v]", config.Heartbeats, err)
+	}
+
+	if _, err = exportConfig(heartbeats, []string{"[web"}); err == nil {
+		t.Errorf("Invalid pattern accepted")
+	}
+}
+
+func TestExportConfigEscapesTemplates(t *testing.T) {
+	heartbeats := []Heartbeat{{Name: "{{.Hostname}}-backup", Description: "literal {{ braces }}", AlertMessage: "{{x", Enabled: true}}
+	config, err := exportConfig(heartbeats, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.expandTemplates()
+	exported := config.Heartbeats[0]
+	if err := config.templateErrors(); err != nil || exported.Name != heartbeats[0].Name || exported.Description != heartbeats[0].Description || exported.AlertMessage != heartbeats[0].AlertMessage {
+		t.Errorf("Exported heartbeat [%+v] does not load as [%+v] [%v]", exported, heartbeats[0], err)
+	}
+}
@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package opsgenie
+
+import (
+	"os"
+	"syscall"
+)
+
+//lockFile takes an exclusive lock on the file, without wait it returns errLocked when another process holds it
+func lockFile(file *os.File, wait bool) error {
+	how := syscall.LOCK_EX
+	if !wait {
+		how |= syscall.LOCK_NB
+	}
+	err := syscall.Flock(int(file.Fd()), how)
+	if err == syscall.EWOULDBLOCK {
+		return errLocked
+	}
+	return err
+}
+
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,48 @@
+//go:build windows
+// +build windows
+
+package opsgenie
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+	errorLockViolation      = syscall.Errno(33)
+)
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+//lockFile takes an exclusive lock on the file, without wait it returns errLocked when another process holds it
+func lockFile(file *os.File, wait bool) error {
+	flags := uintptr(lockfileExclusiveLock)
+	if !wait {
+		flags |= lockfileFailImmediately
+	}
+	overlapped := &syscall.Overlapped{}
+	r, _, err := procLockFileEx.Call(file.Fd(), flags, 0, 1, 0, uintptr(unsafe.Pointer(overlapped)))
+	if r == 0 {
+		if err == errorLockViolation {
+			return errLocked
+		}
+		return err
+	}
+	return nil
+}
+
+func unlockFile(file *os.File) error {
+	overlapped := &syscall.Overlapped{}
+	r, _, err := procUnlockFileEx.Call(file.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(overlapped)))
+	if r == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package opsgenie
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/codegangsta/cli"
+)
+
+const historyFile = "history.jsonl"
+const stateDirMandatory = "[stateDir] is mandatory"
+
+var historyDir string
+var historyRetention = 30 * 24 * time.Hour
+
+//historyMu serializes the goroutines of this process, the lock file serializes processes sharing the state dir
+var historyMu sync.Mutex
+
+//compactionSlack is how much older than the retention the oldest record may get before the file is compacted
+const compactionSlack = time.Hour
+
+//historyRecord is a single attempt to call OpsGenie for a heartbeat
+type historyRecord struct {
+	Time      time.Time     `json:"time"`
+	Heartbeat string        `json:"heartbeat"`
+	Action    string        `json:"action"`
+	Success   bool          `json:"success"`
+	Status    int           `json:"status,omitempty"`
+	Latency   time.Duration `json:"latency"`
+	Error     string        `json:"error,omitempty"`
+}
+
+//SetHistory records every attempt in the history file in dir, records older than retention are removed
+func SetHistory(dir string, retention time.Duration) {
+	historyDir = dir
+	if retention > 0 {
+		historyRetention = retention
+	}
+}
+
+func recordHistory(fields log.Fields, latency time.Duration, err error) {
+	name, _ := fields["heartbeat"].(string)
+	if historyDir == "" || name == "" {
+		return
+	}
+	record := historyRecord{Time: time.Now(), Heartbeat: name, Latency: latency, Success: err == nil}
+	record.Action, _ = fields["action"].(string)
+	record.Status, _ = fields["status"].(int)
+	if err != nil {
+		record.Error = redactText(redactError(err).Error())
+	}
+	historyErr := withHistoryLock(historyDir, func() error {
+		err := appendHistory(historyDir, record)
+		if err != nil {
+			return err
+		}
+		before := time.Now().Add(-historyRetention)
+		if oldest, err := oldestHistory(historyDir); err == nil && oldest.Before(before.Add(-compactionSlack)) {
+			return compactHistory(historyDir, before)
+		}
+		return nil
+	})
+	if historyErr != nil {
+		log.WithFields(fields).Warnf("Could not record history: %s", historyErr)
+	}
+}
+
+func withHistoryLock(dir string, fn func() error) error {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(dir, historyFile+".lock"), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	err = lockFile(file, true)
+	if err != nil {
+		return err
+	}
+	defer unlockFile(file)
+	return fn()
+}
+
+//oldestHistory returns the time of the first record, records are appended so it is the oldest
+func oldestHistory(dir string) (time.Time, error) {
+	file, err := os.Open(filepath.Join(dir, historyFile))
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer file.Close()
+	line, err := bufio.NewReader(file).ReadBytes('\n')
+	if err != nil {
+		return time.Time{}, err
+	}
+	record := historyRecord{}
+	err = json.Unmarshal(line, &record)
+	return record.Time, err
+}
+
+func appendHistory(dir string, record historyRecord) error {
+	content, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(dir, historyFile), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(content, '\n'))
+	return err
+}
+
+//readHistory returns the records from since until until for the heartbeat with name, or all heartbeats when name is empty
+func readHistory(dir string, name string, since time.Time, until time.Time) ([]historyRecord, error) {
+	file, err := os.Open(filepath.Join(dir, historyFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var records []historyRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		record := historyRecord{}
+		if json.Unmarshal(scanner.Bytes(), &record) != nil {
+			continue
+		}
+		if (name == "" || record.Heartbeat == name) && !record.Time.Before(since) && !record.Time.After(until) {
+			records = append(records, record)
+		}
+	}
+	return records, scanner.Err()
+}
+
+func compactHistory(dir string, before time.Time) error {
+	records, err := readHistory(dir, "", before, time.Now().Add(time.Hour))
+	if err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile(dir, historyFile+".tmp")
+	if err != nil {
+		return err
+	}
+	tmp := file.Name()
+	writer := bufio.NewWriter(file)
+	for _, record := range records {
+		content, _ := json.Marshal(record)
+		writer.Write(append(content, '\n'))
+	}
+	err = writer.Flush()
+	file.Close()
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, historyFile))
+}
+
+type historySummary struct {
+	Heartbeat   string
+	Attempts    int
+	SuccessRate float64
+	P95Latency  time.Duration
+}
+
+func summarizeHistory(records []historyRecord) []historySummary {
+	byHeartbeat := make(map[string][]historyRecord)
+	var names []string
+	for _, record := range records {
+		if _, ok := byHeartbeat[record.Heartbeat]; !ok {
+			names = append(names, record.Heartbeat)
+		}
+		byHeartbeat[record.Heartbeat] = append(byHeartbeat[record.Heartbeat], record)
+	}
+	sort.Strings(names)
+	var summaries []historySummary
+	for _, name := range names {
+		heartbeatRecords := byHeartbeat[name]
+		successes := 0
+		latencies := make([]time.Duration, len(heartbeatRecords))
+		for i, record := range heartbeatRecords {
+			if record.Success {
+				successes++
+			}
+			latencies[i] = record.Latency
+		}
+		summaries = append(summaries, historySummary{name, len(heartbeatRecords), float64(successes) / float64(len(heartbeatRecords)), percentile(latencies, 0.95)})
+	}
+	return summaries
+}
+
+//percentile uses the nearest rank method
+func percentile(values []time.Duration, p float64) time.Duration {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, len(values))
+	copy(sorted, values)
+	sort.Sort(durations(sorted))
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+type durations []time.Duration
+
+func (d durations) Len() int           { return len(d) }
+func (d durations) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+func (d durations) Less(i, j int) bool { return d[i] < d[j] }
+
+func printHistory(w io.Writer, records []historyRecord) {
+	for _, record := range records {
+		result := "ok"
+		if !record.Success {
+			result = "failed: " + record.Error
+		}
+		fmt.Fprintf(w, "%s  %-30s %-8s %-10s %s\n", record.Time.Format(time.RFC3339), record.Heartbeat, record.Action, record.Latency, result)
+	}
+	for _, summary := range summarizeHistory(records) {
+		fmt.Fprintf(w, "%s: %d attempts, %.1f%% successful, p95 latency %s\n", summary.Heartbeat, summary.Attempts, summary.SuccessRate*100, summary.P95Latency)
+	}
+}
+
+//parseTimeArg accepts a duration before now like 24h or an RFC3339 time
+func parseTimeArg(value string, now time.Time) (time.Time, error) {
+	if duration, err := time.ParseDuration(value); err == nil {
+		return now.Add(-duration), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("[%s] should be a duration like 24h or a time like 2006-01-02T15:04:05Z", value)
+	}
+	return t, nil
+}
+
+func showHistory(c *cli.Context) {
+	if historyDir == "" {
+		logAndExit(stateDirMandatory)
+		return
+	}
+	now := time.Now()
+	since, err := parseTimeArg(c.String("since"), now)
+	if err != nil {
+		logAndExit(err.Error())
+		return
+	}
+	until, err := parseTimeArg(c.String("until"), now)
+	if err != nil {
+		logAndExit(err.Error())
+		return
+	}
+	name, err := nameArg(c)
+	if err != nil {
+		logAndExit(err.Error())
+		return
+	}
+	records, err := readHistory(historyDir, name, since, until)
+	if err != nil {
+		logAndExit(err.Error())
+		return
+	}
+	printHistory(os.Stdout, records)
+}
@@ -0,0 +1,154 @@
+package opsgenie
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+func TestRecordAndReadHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	SetHistory(dir, 0)
+	defer SetHistory("", 0)
+
+	recordHistory(log.Fields{"heartbeat": "first", "action": "send", "status": 200}, 10*time.Millisecond, nil)
+	recordHistory(log.Fields{"heartbeat": "second", "action": "send"}, 20*time.Millisecond, errors.New("timeout"))
+	recordHistory(log.Fields{"heartbeat": "", "action": "list"}, 20*time.Millisecond, nil)
+
+	records, err := readHistory(dir, "", time.Now().Add(-time.Minute), time.Now())
+	if err != nil || len(records) != 2 {
+		t.Fatalf("Read [%+v] [%v]", records, err)
+	}
+	if records[0].Heartbeat != "first" || !records[0].Success || records[0].Status != 200 || records[0].Latency != 10*time.Millisecond {
+		t.Errorf("First record not correct [%+v]", records[0])
+	}
+	if records[1].Heartbeat != "second" || records[1].Success || records[1].Error != "timeout" {
+		t.Errorf("Second record not correct [%+v]", records[1])
+	}
+	records, _ = readHistory(dir, "second", time.Now().Add(-time.Minute), time.Now())
+	if len(records) != 1 {
+		t.Errorf("Records not filtered by name [%+v]", records)
+	}
+}
+
+func TestCompactHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	appendHistory(dir, historyRecord{Time: time.Now().Add(-48 * time.Hour), Heartbeat: "old"})
+	appendHistory(dir, historyRecord{Time: time.Now(), Heartbeat: "new"})
+	err = compactHistory(dir, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, _ := readHistory(dir, "", time.Time{}, time.Now())
+	if len(records) != 1 || records[0].Heartbeat != "new" {
+		t.Errorf("History not compacted [%+v]", records)
+	}
+}
+
+func TestSummarizeHistory(t *testing.T) {
+	var records []historyRecord
+	for i := 1; i <= 20; i++ {
+		records = append(records, historyRecord{Heartbeat: "test", Success: i != 7, Latency: time.Duration(i) * time.Millisecond})
+	}
+	summaries := summarizeHistory(records)
+	if len(summaries) != 1 || summaries[0].Attempts != 20 || summaries[0].SuccessRate != 0.95 || summaries[0].P95Latency != 19*time.Millisecond {
+		t.Errorf("Summary not correct [%+v]", summaries)
+	}
+}
+
+func TestParseTimeArg(t *testing.T) {
+	now := time.Now()
+	if since, err := parseTimeArg("2h", now); err != nil || !since.Equal(now.Add(-2*time.Hour)) {
+		t.Errorf("Duration not parsed [%s] [%v]", since, err)
+	}
+	if since, err := parseTimeArg("2016-03-01T10:00:00Z", now); err != nil || since.Unix() != 1456826400 {
+		t.Errorf("Time not parsed [%s] [%v]", since, err)
+	}
+	if _, err := parseTimeArg("yesterday", now); err == nil {
+		t.Errorf("Wrong value accepted")
+	}
+}
+
+func TestRecordHistoryRedacted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	SetHistory(dir, 0)
+	defer SetHistory("", 0)
+
+	failed := &url.Error{Op: "Get", URL: "https://api.opsgenie.com/v1/json/heartbeat?apiKey=secretApiKey&name=first", Err: errors.New("connection refused")}
+	recordHistory(log.Fields{"heartbeat": "first", "action": "get"}, time.Millisecond, failed)
+	content, _ := ioutil.ReadFile(filepath.Join(dir, historyFile))
+	if strings.Contains(string(content), "secretApiKey") || !strings.Contains(string(content), "connection refused") {
+		t.Errorf("History not redacted [%s]", content)
+	}
+}
+
+func TestRecordHistoryConcurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	SetHistory(dir, time.Hour)
+	defer SetHistory("", 0)
+	defer func() { historyRetention = 30 * 24 * time.Hour }()
+
+	appendHistory(dir, historyRecord{Time: time.Now().Add(-48 * time.Hour), Heartbeat: "old"})
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recordHistory(log.Fields{"heartbeat": "new", "action": "send"}, time.Millisecond, nil)
+		}()
+	}
+	wg.Wait()
+	records, _ := readHistory(dir, "", time.Time{}, time.Now())
+	if len(records) != 20 {
+		t.Errorf("Records lost or not compacted, found [%d]", len(records))
+	}
+	files, _ := ioutil.ReadDir(dir)
+	for _, file := range files {
+		if strings.Contains(file.Name(), ".tmp") {
+			t.Errorf("Temporary file [%s] left behind", file.Name())
+		}
+	}
+}
+
+func TestOldestHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := oldestHistory(dir); err == nil {
+		t.Error("Oldest record found without history")
+	}
+	oldest := time.Now().Add(-time.Hour).Round(time.Second)
+	appendHistory(dir, historyRecord{Time: oldest, Heartbeat: "first"})
+	appendHistory(dir, historyRecord{Time: time.Now(), Heartbeat: "second"})
+	if found, err := oldestHistory(dir); err != nil || !found.Equal(oldest) {
+		t.Errorf("Oldest record is [%s] [%v] but should be [%s]", found, err, oldest)
+	}
+}
@@ -0,0 +1,68 @@
+package opsgenie
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"time"
+)
+
+//jitterRand is seeded per process so hosts started at the same moment get different jitter
+var jitterRand = rand.New(rand.NewSource(time.Now().UnixNano() ^ int64(os.Getpid())))
+
+//randomJitter returns a random duration between 0 and max
+func randomJitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(jitterRand.Int63n(int64(max)))
+}
+
+//staggerOffset hashes host and name into the loop interval, so hosts sharing a config send at different moments
+//that stay the same between restarts
+func staggerOffset(host string, name string, loopInterval time.Duration) time.Duration {
+	if loopInterval <= 0 {
+		return 0
+	}
+	hash := fnv.New64a()
+	hash.Write([]byte(host + "/" + name))
+	return time.Duration(hash.Sum64() % uint64(loopInterval))
+}
+
+func loopOffset(args OpsArgs) time.Duration {
+	if !args.Stagger {
+		return 0
+	}
+	host, _ := os.Hostname()
+	return staggerOffset(host, args.Name, args.LoopInterval)
+}
+
+//sendHeartbeatInterval calls tick right away and then every loop interval counted from the start, delayed by the stagger
+//offset and a random jitter per tick. The jitter does not add up, on average tick is still called once per loop interval.
+func sendHeartbeatInterval(args OpsArgs, tick func()) {
+	if args.LoopInterval <= 0 {
+		args.LoopInterval = loopIntervalFor(args)
+	}
+	start := time.Now()
+	tick()
+	//the stagger offset applies from the second tick, so the first ping is not delayed
+	start = start.Add(loopOffset(args))
+	for slot := 0; ; {
+		slot = nextSlot(start, args.LoopInterval, slot, time.Now())
+		next := start.Add(time.Duration(slot)*args.LoopInterval + randomJitter(args.Jitter))
+		time.Sleep(next.Sub(time.Now()))
+		tick()
+	}
+}
+
+//nextSlot returns the slot after last, or when ticks ran longer than the loop interval the first slot that did not start
+//yet, so missed slots are skipped like time.Ticker does instead of sent in a burst
+func nextSlot(start time.Time, loopInterval time.Duration, last int, now time.Time) int {
+	slot := last + 1
+	if elapsed := now.Sub(start); elapsed >= 0 {
+		if missed := int(elapsed / loopInterval); missed >= slot {
+			slot = missed + 1
+		}
+	}
+	return slot
+}
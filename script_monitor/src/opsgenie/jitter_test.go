@@ -0,0 +1,82 @@
+package opsgenie
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRandomJitter(t *testing.T) {
+	if jitter := randomJitter(0); jitter != 0 {
+		t.Errorf("Jitter without max is [%s]", jitter)
+	}
+	for i := 0; i < 100; i++ {
+		if jitter := randomJitter(time.Second); jitter < 0 || jitter >= time.Second {
+			t.Fatalf("Jitter [%s] is out of range", jitter)
+		}
+	}
+}
+
+func TestStaggerOffset(t *testing.T) {
+	offset := staggerOffset("host1", "backup", time.Minute)
+	if offset < 0 || offset >= time.Minute {
+		t.Errorf("Offset [%s] is out of range", offset)
+	}
+	if staggerOffset("host1", "backup", time.Minute) != offset {
+		t.Error("Offset should be the same for the same host and name")
+	}
+	offsets := make(map[time.Duration]bool)
+	for _, host := range []string{"host1", "host2", "host3", "host4", "host5"} {
+		offsets[staggerOffset(host, "backup", time.Minute)] = true
+	}
+	if len(offsets) < 4 {
+		t.Errorf("Offsets of different hosts are not spread [%v]", offsets)
+	}
+	if staggerOffset("host1", "backup", 0) != 0 {
+		t.Error("Offset without loop interval should be 0")
+	}
+}
+
+func TestLoopOffsetWithoutStagger(t *testing.T) {
+	if offset := loopOffset(OpsArgs{Name: "backup", LoopInterval: time.Minute}); offset != 0 {
+		t.Errorf("Offset without stagger is [%s]", offset)
+	}
+}
+
+func TestNextSlot(t *testing.T) {
+	start := time.Now()
+	tests := []struct {
+		last    int
+		elapsed time.Duration
+		slot    int
+	}{
+		{0, -5 * time.Second, 1},
+		{0, 5 * time.Second, 1},
+		{1, 15 * time.Second, 2},
+		{1, 35 * time.Second, 4},
+		{4, time.Hour, 361},
+	}
+	for _, test := range tests {
+		if slot := nextSlot(start, 10*time.Second, test.last, start.Add(test.elapsed)); slot != test.slot {
+			t.Errorf("Slot after [%d] at [%s] is [%d] but should be [%d]", test.last, test.elapsed, slot, test.slot)
+		}
+	}
+}
+
+func TestSendHeartbeatIntervalFirstTickRightAway(t *testing.T) {
+	ticks := make(chan time.Time, 1)
+	start := time.Now()
+	go sendHeartbeatInterval(OpsArgs{Name: "backup", LoopInterval: -time.Second, Stagger: true}, func() {
+		select {
+		case ticks <- time.Now():
+		default:
+		}
+	})
+	select {
+	case at := <-ticks:
+		if at.Sub(start) > time.Second {
+			t.Errorf("First tick after [%s]", at.Sub(start))
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("First tick not called right away")
+	}
+}
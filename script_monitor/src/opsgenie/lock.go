@@ -0,0 +1,172 @@
+package opsgenie
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+var errLocked = errors.New("Locked by another process")
+
+//Lock makes sure only one replica of a loop sends a shared heartbeat
+type Lock interface {
+	//Acquire gets or renews the lock and reports whether this replica holds it
+	Acquire() (bool, error)
+}
+
+//newLock returns the lock configured in args, or nil when every replica should send
+func newLock(args OpsArgs) Lock {
+	if args.LockFile != "" {
+		return &fileLock{path: args.LockFile}
+	}
+	if args.LockURL != "" {
+		return &httpLease{url: args.LockURL, holder: lockHolder(), lease: args.LeaseTime}
+	}
+	return nil
+}
+
+func lockHolder() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+//holdsLock acquires the lock before every tick of a loop
+func holdsLock(lock Lock, name string) bool {
+	if lock == nil {
+		return true
+	}
+	held, err := lock.Acquire()
+	if err != nil {
+		log.WithFields(requestFields("lock", name)).Error(err)
+	}
+	if !held {
+		log.WithFields(requestFields("lock", name)).Debugf("Not holding the lock, not sending heartbeat [%s]", name)
+	}
+	return held
+}
+
+//fileLock uses flock, or LockFileEx on windows, on a file, on shared storage the lock moves to another replica as soon as the holder dies
+type fileLock struct {
+	path string
+	file *os.File
+}
+
+func (l *fileLock) Acquire() (bool, error) {
+	if l.file != nil {
+		return true, nil
+	}
+	file, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return false, err
+	}
+	err = lockFile(file, false)
+	if err == errLocked {
+		file.Close()
+		return false, nil
+	}
+	if err != nil {
+		file.Close()
+		return false, err
+	}
+	err = file.Truncate(0)
+	if err == nil {
+		_, err = file.WriteString(lockHolder() + "\n")
+	}
+	if err != nil {
+		unlockFile(file)
+		file.Close()
+		return false, err
+	}
+	l.file = file
+	return true, nil
+}
+
+//httpLease renews a lease with a POST of {"holder": ..., "leaseSeconds": ...} to url, the server answers 200 when
+//the holder has the lease and 409 when another holder has it. The lease moves to another replica when it is not
+//renewed within the lease time. When the server can't be reached the holder keeps the lease until it expires.
+type httpLease struct {
+	url    string
+	holder string
+	lease  time.Duration
+
+	mu       sync.Mutex
+	expires  time.Time
+	renewing bool
+}
+
+//Acquire renews the lease the first time and then starts renewing it every third of the lease time, apart from the
+//ticks of the loop, so the lease does not lapse when it is shorter than the loop interval or the time between schedules
+func (l *httpLease) Acquire() (bool, error) {
+	l.mu.Lock()
+	renewing := l.renewing
+	l.renewing = true
+	l.mu.Unlock()
+	if renewing {
+		return l.held(time.Now()), nil
+	}
+	held, err := l.renew()
+	go l.renewLoop()
+	return held, err
+}
+
+func (l *httpLease) renewLoop() {
+	ticker := time.NewTicker(l.lease / 3)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := l.renew(); err != nil {
+			log.WithFields(log.Fields{"action": "lock", "lockUrl": redactText(l.url)}).Error(err)
+		}
+	}
+}
+
+func (l *httpLease) held(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return now.Before(l.expires)
+}
+
+func (l *httpLease) setExpires(expires time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.expires = expires
+}
+
+//renew posts the lease once and reports whether this replica holds it
+func (l *httpLease) renew() (bool, error) {
+	body, err := json.Marshal(map[string]interface{}{"holder": l.holder, "leaseSeconds": int(l.lease.Seconds())})
+	if err != nil {
+		return false, err
+	}
+	request, err := http.NewRequest("POST", l.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request, cancel := withTimeout(request)
+	defer cancel()
+	start := time.Now()
+	resp, err := getHTTPClient().Do(request)
+	if err != nil {
+		return l.held(start), err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		l.setExpires(start.Add(l.lease))
+		return true, nil
+	case http.StatusConflict:
+		l.setExpires(time.Time{})
+		return false, nil
+	}
+	return l.held(start), fmt.Errorf("Lease [%s] answered with HTTP status [%d]", l.url, resp.StatusCode)
+}
@@ -0,0 +1,133 @@
+package opsgenie
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	first := &fileLock{path: dir + "/heartbeat.lock"}
+	second := &fileLock{path: dir + "/heartbeat.lock"}
+
+	if held, err := first.Acquire(); !held || err != nil {
+		t.Fatalf("First lock not acquired [%v]", err)
+	}
+	if held, err := second.Acquire(); held || err != nil {
+		t.Errorf("Second lock acquired while first holds it [%v]", err)
+	}
+	if held, _ := first.Acquire(); !held {
+		t.Errorf("First lock not renewed")
+	}
+	first.file.Close()
+	if held, err := second.Acquire(); !held || err != nil {
+		t.Errorf("Second lock not acquired after first released it [%v]", err)
+	}
+}
+
+func TestHTTPLease(t *testing.T) {
+	owner := ""
+	available := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		request := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&request)
+		holder := request["holder"].(string)
+		if owner != "" && owner != holder {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		owner = holder
+	}))
+	defer server.Close()
+	first := &httpLease{url: server.URL, holder: "first", lease: time.Minute}
+	second := &httpLease{url: server.URL, holder: "second", lease: time.Minute}
+
+	if held, err := first.renew(); !held || err != nil {
+		t.Fatalf("First lease not acquired [%v]", err)
+	}
+	if held, err := second.renew(); held || err != nil {
+		t.Errorf("Second lease acquired while first holds it [%v]", err)
+	}
+	available = false
+	if held, err := first.renew(); !held || err == nil {
+		t.Errorf("First lease should be kept until it expires when the server fails [%v]", err)
+	}
+	if held, _ := second.renew(); held {
+		t.Errorf("Second lease acquired while the server fails")
+	}
+	first.setExpires(time.Now())
+	if held, _ := first.renew(); held {
+		t.Errorf("Expired lease still held while the server fails")
+	}
+}
+
+func TestHTTPLeaseRenewsApartFromTicks(t *testing.T) {
+	var renewals int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&renewals, 1)
+	}))
+	//the lease keeps renewing for the rest of the tests, so the server stays up
+	lease := &httpLease{url: server.URL, holder: "first", lease: 60 * time.Millisecond}
+
+	if held, err := lease.Acquire(); !held || err != nil {
+		t.Fatalf("Lease not acquired [%v]", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if held, err := lease.Acquire(); !held || err != nil || atomic.LoadInt32(&renewals) < 4 {
+		t.Errorf("Lease not renewed between ticks, held [%t] after [%d] renewals [%v]", held, atomic.LoadInt32(&renewals), err)
+	}
+}
+
+func TestNewLock(t *testing.T) {
+	if newLock(OpsArgs{}) != nil {
+		t.Errorf("Lock created without configuration")
+	}
+	if _, ok := newLock(OpsArgs{LockFile: "/tmp/test.lock"}).(*fileLock); !ok {
+		t.Errorf("File lock not created")
+	}
+	if _, ok := newLock(OpsArgs{LockURL: "http://localhost/lease", LeaseTime: time.Minute}).(*httpLease); !ok {
+		t.Errorf("HTTP lease not created")
+	}
+}
+
+type testLock struct {
+	held bool
+}
+
+func (l *testLock) Acquire() (bool, error) {
+	return l.held, nil
+}
+
+func TestLoopTickStartsOnlyWithLock(t *testing.T) {
+	server, paths := startTestServer()
+	defer stopTestServer(server)
+	lock := &testLock{}
+	tick := loopTick(testargs, lock, true)
+
+	tick()
+	if len(*paths) != 0 {
+		t.Errorf("Replica without lock called OpsGenie [%v]", *paths)
+	}
+	lock.held = true
+	tick()
+	tick()
+	expected := []string{"/v1/json/heartbeat/", "/v1/json/heartbeat", "/v1/json/heartbeat/send", "/v1/json/heartbeat/send"}
+	if !reflect.DeepEqual(*paths, expected) {
+		t.Errorf("Requests are [%v] but should be [%v]", *paths, expected)
+	}
+}
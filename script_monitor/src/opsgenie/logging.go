@@ -0,0 +1,48 @@
+package opsgenie
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/codegangsta/cli"
+)
+
+//ConfigureLogging sets up the logger from the logging flags, it is meant to be used as the application Before func
+func ConfigureLogging(c *cli.Context) error {
+	level, err := log.ParseLevel(c.GlobalString("logLevel"))
+	if err != nil {
+		return err
+	}
+	log.SetLevel(level)
+
+	switch c.GlobalString("logFormat") {
+	case "text":
+		log.SetFormatter(&log.TextFormatter{})
+	case "json":
+		log.SetFormatter(&log.JSONFormatter{})
+	default:
+		return fmt.Errorf("[logFormat] can only be one of the following: text or json")
+	}
+
+	if c.GlobalString("logFile") != "" {
+		file, err := os.OpenFile(c.GlobalString("logFile"), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		log.SetOutput(file)
+	}
+
+	if c.GlobalString("syslog") != "" {
+		hook, err := createSyslogHook(c.GlobalString("syslog"), c.App.Name)
+		if err != nil {
+			return err
+		}
+		log.AddHook(hook)
+	}
+	return nil
+}
+
+func requestFields(action string, name string) log.Fields {
+	return log.Fields{"heartbeat": name, "action": action}
+}
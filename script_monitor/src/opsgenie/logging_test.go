@@ -0,0 +1,65 @@
+package opsgenie
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/codegangsta/cli"
+)
+
+func createLoggingCli(level string, format string, file string) *cli.Context {
+	globalSet := flag.NewFlagSet("testGlobal", 0)
+	globalSet.String("logLevel", level, "")
+	globalSet.String("logFormat", format, "")
+	globalSet.String("logFile", file, "")
+	globalSet.String("syslog", "", "")
+	return cli.NewContext(cli.NewApp(), nil, globalSet)
+}
+
+func resetLogging() {
+	log.SetLevel(log.InfoLevel)
+	log.SetFormatter(&log.TextFormatter{})
+	log.SetOutput(os.Stderr)
+}
+
+func TestConfigureLogging(t *testing.T) {
+	defer resetLogging()
+	file, err := ioutil.TempFile("", "script_monitor.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+
+	err = ConfigureLogging(createLoggingCli("debug", "json", file.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if log.GetLevel() != log.DebugLevel {
+		t.Errorf("Log level not set, is [%s]", log.GetLevel())
+	}
+	log.WithFields(requestFields("send", "testName")).Info("test")
+	content, _ := ioutil.ReadFile(file.Name())
+	if string(content) == "" || content[0] != '{' {
+		t.Errorf("Log file does not contain json [%s]", content)
+	}
+}
+
+func TestConfigureLoggingWrongValues(t *testing.T) {
+	defer resetLogging()
+	if ConfigureLogging(createLoggingCli("loud", "text", "")) == nil {
+		t.Errorf("Wrong log level accepted")
+	}
+	if ConfigureLogging(createLoggingCli("info", "xml", "")) == nil {
+		t.Errorf("Wrong log format accepted")
+	}
+}
+
+func TestCreateSyslogHookWrongTarget(t *testing.T) {
+	if _, err := createSyslogHook("localhost", "test"); err == nil {
+		t.Errorf("Syslog target without network accepted")
+	}
+}
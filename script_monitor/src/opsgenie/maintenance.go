@@ -0,0 +1,151 @@
+package opsgenie
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const maintenanceTimeLayout = "2006-01-02 15:04"
+
+//MaintenanceWindow is either recurring, a cron Schedule for the start with a Duration, or a one-off range from Start to End
+type MaintenanceWindow struct {
+	Schedule string `json:"schedule,omitempty"`
+	Duration string `json:"duration,omitempty"`
+	Start    string `json:"start,omitempty"`
+	End      string `json:"end,omitempty"`
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+func (w MaintenanceWindow) validate() error {
+	_, err := w.active(time.Now())
+	return err
+}
+
+//active returns true when t falls inside the window, a recurring window is active when it started less than Duration before t
+func (w MaintenanceWindow) active(t time.Time) (bool, error) {
+	if w.Schedule != "" {
+		schedule, err := parseCron(w.Schedule, w.TimeZone)
+		if err != nil {
+			return false, err
+		}
+		duration, err := time.ParseDuration(w.Duration)
+		if err != nil || duration <= 0 {
+			return false, fmt.Errorf("Maintenance window [%s] needs a positive duration", w.Schedule)
+		}
+		start := schedule.next(t.Add(-duration))
+		return !start.IsZero() && !start.After(t), nil
+	}
+	location := time.Local
+	if w.TimeZone != "" {
+		var err error
+		location, err = time.LoadLocation(w.TimeZone)
+		if err != nil {
+			return false, err
+		}
+	}
+	start, err := time.ParseInLocation(maintenanceTimeLayout, w.Start, location)
+	if err != nil {
+		return false, fmt.Errorf("Maintenance window start [%s] should look like [%s]", w.Start, maintenanceTimeLayout)
+	}
+	end, err := time.ParseInLocation(maintenanceTimeLayout, w.End, location)
+	if err != nil || !end.After(start) {
+		return false, fmt.Errorf("Maintenance window end [%s] should look like [%s] and be after the start", w.End, maintenanceTimeLayout)
+	}
+	return !t.Before(start) && t.Before(end), nil
+}
+
+func inMaintenance(windows []MaintenanceWindow, t time.Time) bool {
+	for _, window := range windows {
+		if active, _ := window.active(t); active {
+			return true
+		}
+	}
+	return false
+}
+
+//maintenanceState records that the loop disabled the heartbeat, it is kept in the state directory so a restart resumes correctly
+type maintenanceState struct {
+	Disabled bool      `json:"disabled"`
+	Since    time.Time `json:"since"`
+}
+
+type maintenanceTracker struct {
+	args  OpsArgs
+	state maintenanceState
+}
+
+func newMaintenanceTracker(args OpsArgs) *maintenanceTracker {
+	tracker := &maintenanceTracker{args: args}
+	if args.StateDir != "" {
+		err := readState(tracker.stateFile(), &tracker.state)
+		if err != nil {
+			log.WithFields(requestFields("maintenance", args.Name)).Error(err)
+		}
+	}
+	return tracker
+}
+
+func (m *maintenanceTracker) stateFile() string {
+	return stateFile(m.args.StateDir, "maintenance", m.args.Name)
+}
+
+//start adds or updates the heartbeat when the loop starts. During a window it is updated disabled, so a loop restarted
+//in the middle of a window does not enable a heartbeat that won't be sent.
+func (m *maintenanceTracker) start(now time.Time) {
+	if !inMaintenance(m.args.Maintenance, now) {
+		startHeartbeat(m.args)
+		return
+	}
+	fields := requestFields("get", m.args.Name)
+	heartbeat, err := getHeartbeat(m.args, fields)
+	if err != nil {
+		log.WithFields(fields).Error(err)
+		return
+	}
+	if heartbeat == nil {
+		//a new heartbeat is added enabled, the next tick disables it
+		if addHeartbeat(m.args) == nil {
+			m.save(maintenanceState{false, now})
+		}
+		return
+	}
+	if updateHeartbeat(m.args, *heartbeat, false) == nil {
+		log.WithFields(requestFields("maintenance", m.args.Name)).Infof("Maintenance window active, disabled heartbeat [%s]", m.args.Name)
+		m.save(maintenanceState{true, now})
+	}
+}
+
+//tick disables the heartbeat when a window starts, skips sending during the window and enables and sends when it ends
+func (m *maintenanceTracker) tick(now time.Time) {
+	fields := requestFields("maintenance", m.args.Name)
+	active := inMaintenance(m.args.Maintenance, now)
+	switch {
+	case active && !m.state.Disabled:
+		if disableHeartbeat(m.args) == nil {
+			log.WithFields(fields).Infof("Maintenance window started, disabled heartbeat [%s]", m.args.Name)
+			m.save(maintenanceState{true, now})
+		}
+	case active:
+		log.WithFields(fields).Debugf("Maintenance window active, not sending heartbeat [%s]", m.args.Name)
+	case m.state.Disabled:
+		if enableHeartbeat(m.args) == nil {
+			log.WithFields(fields).Infof("Maintenance window ended, enabled heartbeat [%s]", m.args.Name)
+			m.save(maintenanceState{false, now})
+			sendHeartbeat(m.args)
+		}
+	default:
+		sendHeartbeat(m.args)
+	}
+}
+
+func (m *maintenanceTracker) save(state maintenanceState) {
+	m.state = state
+	if m.args.StateDir != "" {
+		err := writeState(m.stateFile(), state)
+		if err != nil {
+			log.WithFields(requestFields("maintenance", m.args.Name)).Error(err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package opsgenie
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceWindowActive(t *testing.T) {
+	recurring := MaintenanceWindow{Schedule: "0 22 * * SAT", Duration: "2h", TimeZone: "UTC"}
+	oneOff := MaintenanceWindow{Start: "2016-03-01 10:00", End: "2016-03-01 12:00", TimeZone: "Europe/Amsterdam"}
+	tests := []struct {
+		window MaintenanceWindow
+		at     string
+		active bool
+	}{
+		{recurring, "2016-03-05T21:59:00Z", false},
+		{recurring, "2016-03-05T22:00:00Z", true},
+		{recurring, "2016-03-05T23:59:00Z", true},
+		{recurring, "2016-03-06T00:00:00Z", false},
+		{oneOff, "2016-03-01T08:59:00Z", false},
+		{oneOff, "2016-03-01T09:00:00Z", true},
+		{oneOff, "2016-03-01T11:00:00Z", false},
+	}
+	for _, test := range tests {
+		at, _ := time.Parse(time.RFC3339, test.at)
+		active, err := test.window.active(at)
+		if err != nil || active != test.active {
+			t.Errorf("Window [%+v] at [%s] is active [%t] but should be [%t] [%v]", test.window, test.at, active, test.active, err)
+		}
+	}
+}
+
+func TestMaintenanceWindowInvalid(t *testing.T) {
+	windows := []MaintenanceWindow{
+		{Schedule: "0 22 * * SAT"},
+		{Start: "2016-03-01 10:00", End: "2016-03-01 09:00"},
+		{Start: "tomorrow", End: "2016-03-01 09:00"},
+	}
+	for _, window := range windows {
+		if window.validate() == nil {
+			t.Errorf("Window [%+v] should not be valid", window)
+		}
+	}
+}
+
+func TestMaintenanceTrackerResumesFromState(t *testing.T) {
+	server, paths := startTestServer()
+	defer stopTestServer(server)
+	stateDir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(stateDir)
+
+	args := testargs
+	args.StateDir = stateDir
+	args.Maintenance = []MaintenanceWindow{{Start: "2016-03-01 10:00", End: "2016-03-01 12:00", TimeZone: "UTC"}}
+	before, _ := time.Parse(time.RFC3339, "2016-03-01T09:00:00Z")
+	during, _ := time.Parse(time.RFC3339, "2016-03-01T10:30:00Z")
+	after, _ := time.Parse(time.RFC3339, "2016-03-01T12:30:00Z")
+
+	newMaintenanceTracker(args).tick(before)
+	newMaintenanceTracker(args).tick(during)
+	newMaintenanceTracker(args).tick(during)
+	newMaintenanceTracker(args).tick(after)
+	newMaintenanceTracker(args).tick(after)
+
+	expected := []string{"/v1/json/heartbeat/send", "/v1/json/heartbeat/disable", "/v1/json/heartbeat/enable", "/v1/json/heartbeat/send", "/v1/json/heartbeat/send"}
+	if !reflect.DeepEqual(*paths, expected) {
+		t.Errorf("Requests are [%v] but should be [%v]", *paths, expected)
+	}
+}
+
+func TestLoopTickRestartDuringMaintenance(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if strings.Contains(string(body), `"enabled":true`) {
+			requests = append(requests, r.URL.Path+" enabled")
+		} else {
+			requests = append(requests, r.URL.Path)
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer stopTestServer(server)
+	SetAPIURL(server.URL)
+	stateDir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(stateDir)
+
+	args := testargs
+	args.StateDir = stateDir
+	args.Maintenance = []MaintenanceWindow{{Schedule: "* * * * *", Duration: "1h"}}
+	//the previous loop disabled the heartbeat when the window started
+	if err := writeState(stateFile(stateDir, "maintenance", args.Name), maintenanceState{true, time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	tick := loopTick(args, &testLock{held: true}, true)
+	tick()
+	tick()
+
+	expected := []string{"/v1/json/heartbeat/", "/v1/json/heartbeat"}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("Requests are [%v] but should be [%v]", requests, expected)
+	}
+}
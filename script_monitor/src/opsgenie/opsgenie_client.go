@@ -2,11 +2,9 @@ package opsgenie
 
 import (
 	"bytes"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"net"
 	"net/http"
 	"net/url"
 	"time"
@@ -17,82 +15,185 @@ import (
 var timeout = time.Second * 30
 var apiURL = "https://api.opsgenie.com"
 
-func startHeartbeatAndSend(args OpsArgs) {
-	startHeartbeat(args)
-	sendHeartbeat(args)
+func startHeartbeatAndSend(args OpsArgs) error {
+	err := startHeartbeat(args)
+	if err != nil {
+		return err
+	}
+	return sendHeartbeat(args)
 }
 
-func startHeartbeat(args OpsArgs) {
-	heartbeat, err := getHeartbeat(args)
+func startHeartbeat(args OpsArgs) error {
+	fields := requestFields("get", args.Name)
+	heartbeat, err := getHeartbeat(args, fields)
 	if err != nil {
-		log.Error(err)
-	} else {
-		if heartbeat == nil {
-			addHeartbeat(args)
-		} else {
-			updateHeartbeatWithEnabledTrue(args, *heartbeat)
-		}
+		log.WithFields(fields).Error(err)
+		return err
 	}
+	if heartbeat == nil {
+		return addHeartbeat(args)
+	}
+	return updateHeartbeatWithEnabledTrue(args, *heartbeat)
 }
 
 //StartHeartbeatLoop can be used from other codes as a library call
 func StartHeartbeatLoop(args OpsArgs) {
-	startHeartbeat(args)
-	sendHeartbeatLoop(args)
+	sendHeartbeatLoop(args, true)
 }
 
-func getHeartbeat(args OpsArgs) (*Heartbeat, error) {
-	code, body, err := doHTTPRequest("GET", "/v1/json/heartbeat/", mandatoryRequestParams(args), nil)
+func getHeartbeat(args OpsArgs, fields log.Fields) (*Heartbeat, error) {
+	code, body, err := doHTTPRequest("GET", "/v1/json/heartbeat/", mandatoryRequestParams(args), nil, fields)
 	if err != nil {
 		return nil, err
 	}
 	if code != 200 {
-		return checkHeartbeatError(code, body, args.Name)
+		return checkHeartbeatError(code, body, fields)
 	}
-	return createHeartbeat(body, args.Name)
+	return createHeartbeat(body, fields)
 }
 
-func checkHeartbeatError(code int, body []byte, name string) (*Heartbeat, error) {
+func checkHeartbeatError(code int, body []byte, fields log.Fields) (*Heartbeat, error) {
 	errorResponse, err := createErrorResponse(body)
 	if err != nil {
 		return nil, err
 	}
+	fields["errorCode"] = errorResponse.Code
 	if code == 400 && errorResponse.Code == 17 {
-		log.Infof("Heartbeat [%s] doesn't exist", name)
+		log.WithFields(fields).Infof("Heartbeat [%s] doesn't exist", fields["heartbeat"])
 		return nil, nil
 	}
-	return nil, fmt.Errorf("%#v", errorResponse)
+	return nil, errorResponse
 }
 
-func createHeartbeat(body []byte, name string) (*Heartbeat, error) {
+func createHeartbeat(body []byte, fields log.Fields) (*Heartbeat, error) {
 	heartbeat := &Heartbeat{}
 	err := json.Unmarshal(body, &heartbeat)
 	if err != nil {
 		return nil, err
 	}
-	log.Info("Successfully retrieved heartbeat [" + name + "]")
+	log.WithFields(fields).Infof("Successfully retrieved heartbeat [%s]", fields["heartbeat"])
 	return heartbeat, nil
 }
 
-func addHeartbeat(args OpsArgs) {
-	doOpsGenieHTTPRequestHandled("POST", "/v1/json/heartbeat/", nil, allContentParams(args), "Successfully added heartbeat ["+args.Name+"]")
+func listHeartbeats(apiKey string) ([]Heartbeat, error) {
+	body, err := doOpsGenieHTTPRequest("GET", "/v1/json/heartbeat", map[string]string{"apiKey": apiKey}, nil, requestFields("list", ""))
+	if err != nil {
+		return nil, err
+	}
+	list := &heartbeatList{}
+	err = json.Unmarshal(body, list)
+	if err != nil {
+		return nil, err
+	}
+	return list.Heartbeats, nil
+}
+
+func addHeartbeat(args OpsArgs) error {
+	return doOpsGenieHTTPRequestHandled("POST", "/v1/json/heartbeat/", nil, allContentParams(args), requestFields("add", args.Name), "Successfully added heartbeat ["+args.Name+"]")
+}
+
+func updateHeartbeatWithEnabledTrue(args OpsArgs, heartbeat Heartbeat) error {
+	return updateHeartbeat(args, heartbeat, true)
 }
 
-func updateHeartbeatWithEnabledTrue(args OpsArgs, heartbeat Heartbeat) {
+func updateHeartbeat(args OpsArgs, heartbeat Heartbeat, enabled bool) error {
 	var contentParams = allContentParams(args)
 	contentParams["id"] = heartbeat.ID
 	contentParams["name"] = args.Name
-	contentParams["enabled"] = true
-	doOpsGenieHTTPRequestHandled("POST", "/v1/json/heartbeat", nil, contentParams, "Successfully enabled and updated heartbeat ["+args.Name+"]")
+	contentParams["enabled"] = enabled
+	return doOpsGenieHTTPRequestHandled("POST", "/v1/json/heartbeat", nil, contentParams, requestFields("update", args.Name), fmt.Sprintf("Successfully updated heartbeat [%s] with enabled [%t]", args.Name, enabled))
 }
 
-func sendHeartbeat(args OpsArgs) {
-	doOpsGenieHTTPRequestHandled("POST", "/v1/json/heartbeat/send", nil, mandatoryContentParams(args), "Successfully sent heartbeat ["+args.Name+"]")
+func sendHeartbeat(args OpsArgs) error {
+	fields := requestFields("send", args.Name)
+	err := sendHeartbeatWithFields(args, fields)
+	if err != nil {
+		log.WithFields(fields).Error(err)
+	} else {
+		log.WithFields(fields).Info("Successfully sent heartbeat [" + args.Name + "]")
+	}
+	return err
+}
+
+//SendHeartbeat sends a heartbeat and returns the error instead of logging it, an OpsGenie error response is returned as ErrorResponse
+func SendHeartbeat(args OpsArgs) error {
+	return sendHeartbeatWithFields(args, requestFields("send", args.Name))
 }
 
-func sendHeartbeatLoop(args OpsArgs) {
-	for _ = range time.Tick(args.LoopInterval) {
-		sendHeartbeat(args)
+//Retry calls send and retries up to retries times with a delay that doubles every retry, an OpsGenie error response is
+//not retried
+func Retry(send func(OpsArgs) error, args OpsArgs, retries int, delay time.Duration) error {
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		err = send(args)
+		if _, rejected := err.(ErrorResponse); err == nil || rejected {
+			return err
+		}
+	}
+	return err
+}
+
+//sendHeartbeatWithFields closes the alert recorded for the heartbeat once it is alive again
+func sendHeartbeatWithFields(args OpsArgs, fields log.Fields) error {
+	_, err := doOpsGenieHTTPRequest("POST", "/v1/json/heartbeat/send", nil, mandatoryContentParams(args), fields)
+	if err == nil {
+		closeRecordedAlert(args)
+	}
+	return err
+}
+
+//SetAPIURL overrides the OpsGenie API url used for all requests
+func SetAPIURL(url string) {
+	apiURL = url
+}
+
+func sendHeartbeatLoop(args OpsArgs, start bool) {
+	tick := loopTick(args, newLock(args), start)
+	if args.Schedule != "" {
+		sendHeartbeatSchedule(args, tick)
+		return
+	}
+	sendHeartbeatInterval(args, tick)
+}
+
+//loopTick returns what a loop does every tick, with start the heartbeat is added or updated the first time the lock is
+//held so replicas that are not sending leave the heartbeat alone, and not while it is snoozed so it is not enabled early
+func loopTick(args OpsArgs, lock Lock, start bool) func() {
+	tracker := newMaintenanceTracker(args)
+	return func() {
+		if !holdsLock(lock, args.Name) {
+			return
+		}
+		if snoozed(args, time.Now()) {
+			log.WithFields(requestFields("send", args.Name)).Debugf("Heartbeat [%s] is snoozed, not sending", args.Name)
+			return
+		}
+		if start {
+			tracker.start(time.Now())
+			start = false
+		}
+		tracker.tick(time.Now())
+	}
+}
+
+func sendHeartbeatSchedule(args OpsArgs, tick func()) {
+	schedule, err := parseCron(args.Schedule, args.TimeZone)
+	if err != nil {
+		log.WithFields(requestFields("send", args.Name)).Error(err)
+		return
+	}
+	for {
+		next := schedule.next(time.Now())
+		if next.IsZero() {
+			log.WithFields(requestFields("send", args.Name)).Errorf("Schedule [%s] never fires", args.Schedule)
+			return
+		}
+		time.Sleep(next.Sub(time.Now()) + randomJitter(args.Jitter))
+		tick()
 	}
 }
 
@@ -104,12 +205,16 @@ func stopHeartbeat(args OpsArgs) {
 	}
 }
 
-func deleteHeartbeat(args OpsArgs) {
-	doOpsGenieHTTPRequestHandled("DELETE", "/v1/json/heartbeat", mandatoryRequestParams(args), nil, "Successfully deleted heartbeat ["+args.Name+"]")
+func deleteHeartbeat(args OpsArgs) error {
+	return doOpsGenieHTTPRequestHandled("DELETE", "/v1/json/heartbeat", mandatoryRequestParams(args), nil, requestFields("delete", args.Name), "Successfully deleted heartbeat ["+args.Name+"]")
 }
 
-func disableHeartbeat(args OpsArgs) {
-	doOpsGenieHTTPRequestHandled("POST", "/v1/json/heartbeat/disable", nil, mandatoryContentParams(args), "Successfully disabled heartbeat ["+args.Name+"]")
+func disableHeartbeat(args OpsArgs) error {
+	return doOpsGenieHTTPRequestHandled("POST", "/v1/json/heartbeat/disable", nil, mandatoryContentParams(args), requestFields("disable", args.Name), "Successfully disabled heartbeat ["+args.Name+"]")
+}
+
+func enableHeartbeat(args OpsArgs) error {
+	return doOpsGenieHTTPRequestHandled("POST", "/v1/json/heartbeat/enable", nil, mandatoryContentParams(args), requestFields("enable", args.Name), "Successfully enabled heartbeat ["+args.Name+"]")
 }
 
 func mandatoryContentParams(args OpsArgs) map[string]interface{} {
@@ -130,6 +235,18 @@ func allContentParams(args OpsArgs) map[string]interface{} {
 	if args.IntervalUnit != "" {
 		contentParams["intervalUnit"] = args.IntervalUnit
 	}
+	if args.OwnerTeam != "" {
+		contentParams["ownerTeam"] = args.OwnerTeam
+	}
+	if args.AlertMessage != "" {
+		contentParams["alertMessage"] = args.AlertMessage
+	}
+	if len(args.AlertTags) > 0 {
+		contentParams["alertTags"] = args.AlertTags
+	}
+	if args.AlertPriority != "" {
+		contentParams["alertPriority"] = args.AlertPriority
+	}
 	return contentParams
 }
 
@@ -149,44 +266,66 @@ func createErrorResponse(responseBody []byte) (ErrorResponse, error) {
 	return *errResponse, nil
 }
 
-func doOpsGenieHTTPRequestHandled(method string, urlSuffix string, requestParameters map[string]string, contentParameters map[string]interface{}, msg string) {
-	_, err := doOpsGenieHTTPRequest(method, urlSuffix, requestParameters, contentParameters)
+func doOpsGenieHTTPRequestHandled(method string, urlSuffix string, requestParameters map[string]string, contentParameters map[string]interface{}, fields log.Fields, msg string) error {
+	_, err := doOpsGenieHTTPRequest(method, urlSuffix, requestParameters, contentParameters, fields)
 	if err != nil {
-		log.Error(err)
+		log.WithFields(fields).Error(err)
 	} else {
-		log.Info(msg)
+		log.WithFields(fields).Info(msg)
 	}
+	return err
 }
 
-func doOpsGenieHTTPRequest(method string, urlSuffix string, requestParameters map[string]string, contentParameters map[string]interface{}) ([]byte, error) {
-	code, body, err := doHTTPRequest(method, urlSuffix, requestParameters, contentParameters)
+func doOpsGenieHTTPRequest(method string, urlSuffix string, requestParameters map[string]string, contentParameters map[string]interface{}, fields log.Fields) (body []byte, err error) {
+	start := time.Now()
+	defer func() {
+		recordHistory(fields, time.Since(start), err)
+	}()
+	code, body, err := doHTTPRequest(method, urlSuffix, requestParameters, contentParameters, fields)
 	if err != nil {
 		return nil, err
 	}
 	if code != 200 {
 		e, err := createErrorResponse(body)
 		if err != nil {
-			return nil, err
+			return nil, ErrorResponse{Message: string(body)}
 		}
-		return nil, fmt.Errorf("%#v", e)
+		fields["errorCode"] = e.Code
+		return nil, e
 	}
 	return body, nil
 }
 
-func doHTTPRequest(method string, urlSuffix string, requestParameters map[string]string, contentParameters map[string]interface{}) (int, []byte, error) {
+//doHTTPRequest adds the HTTP status and latency of the request to fields
+func doHTTPRequest(method string, urlSuffix string, requestParameters map[string]string, contentParameters map[string]interface{}, fields log.Fields) (int, []byte, error) {
 	request, err := createRequest(method, urlSuffix, requestParameters, contentParameters)
 	if err != nil {
 		return 0, nil, err
 	}
+	request, cancel := withTimeout(request)
+	defer cancel()
+	var timing *requestTiming
+	if debugHTTP {
+		request, timing = traceRequest(request)
+	}
+	start := time.Now()
 	resp, err := getHTTPClient().Do(request)
+	fields["latency"] = time.Since(start).String()
 	if err != nil {
-		return 0, nil, err
+		if debugHTTP {
+			logHTTPExchange(request, nil, nil, timing, err)
+		}
+		return 0, nil, redactError(err)
 	}
+	defer resp.Body.Close()
+	fields["status"] = resp.StatusCode
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return 0, nil, err
 	}
-	defer resp.Body.Close()
+	if debugHTTP {
+		logHTTPExchange(request, resp, body, timing, nil)
+	}
 	return resp.StatusCode, body, nil
 }
 
@@ -220,27 +359,25 @@ func createURL(urlSuffix string, requestParameters map[string]string) (string, e
 	return URL.String(), nil
 }
 
-func getHTTPClient() *http.Client {
-	client := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			Proxy:           http.ProxyFromEnvironment,
-			Dial: func(netw, addr string) (net.Conn, error) {
-				conn, err := net.DialTimeout(netw, addr, timeout)
-				if err != nil {
-					return nil, err
-				}
-				conn.SetDeadline(time.Now().Add(timeout))
-				return conn, nil
-			},
-		},
-	}
-	return client
-}
-
 //Heartbeat represents the OpsGenie heartbeat data structure
 type Heartbeat struct {
-	ID string `json:"id"`
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	Description   string   `json:"description"`
+	Interval      int      `json:"interval"`
+	IntervalUnit  string   `json:"intervalUnit"`
+	Enabled       bool     `json:"enabled"`
+	Expired       bool     `json:"expired"`
+	OwnerTeam     string   `json:"ownerTeam"`
+	AlertMessage  string   `json:"alertMessage"`
+	AlertTags     []string `json:"alertTags"`
+	AlertPriority string   `json:"alertPriority"`
+	//LastHeartbeat is in milliseconds since the epoch
+	LastHeartbeat int64 `json:"lastHeartbeat"`
+}
+
+type heartbeatList struct {
+	Heartbeats []Heartbeat `json:"heartbeats"`
 }
 
 //ErrorResponse represents the OpsGenie error response data structure
@@ -248,3 +385,7 @@ type ErrorResponse struct {
 	Code    int    `json:"code"`
 	Message string `json:"error"`
 }
+
+func (e ErrorResponse) Error() string {
+	return fmt.Sprintf("OpsGenie error response, code [%d] message [%s]", e.Code, e.Message)
+}
@@ -1,18 +1,20 @@
 package opsgenie
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
 
-var testargs = OpsArgs{"testKey", "testName", "testDescription", 99, "month", time.Second * 10, true}
+var testargs = OpsArgs{ApiKey: "testKey", Name: "testName", Description: "testDescription", Interval: 99, IntervalUnit: "month", LoopInterval: time.Second * 10, Delete: true}
 
 func TestCreateUrl(t *testing.T) {
 	var requestParams = make(map[string]string)
 	requestParams["apiKey"] = "test"
 	url, err := createURL("/v1/test", requestParams)
 	if err != nil {
-		t.Errorf(err.Error())
+		t.Error(err)
 	}
 	testURL := "https://api.opsgenie.com/v1/test?apiKey=test"
 	if url != testURL {
@@ -27,6 +29,18 @@ func TestAllContentParams(t *testing.T) {
 	}
 }
 
+func TestAllContentParamsAlertSettings(t *testing.T) {
+	args := testargs
+	if _, ok := allContentParams(args)["ownerTeam"]; ok {
+		t.Error("Empty owner team should not be sent")
+	}
+	args.OwnerTeam, args.AlertMessage, args.AlertTags, args.AlertPriority = "ops", "backup missing", []string{"db"}, "P2"
+	all := allContentParams(args)
+	if all["ownerTeam"] != "ops" || all["alertMessage"] != "backup missing" || all["alertPriority"] != "P2" || len(all["alertTags"].([]string)) != 1 {
+		t.Errorf("Alert settings not in content params [%v]", all)
+	}
+}
+
 func TestMandatoryRequestParams(t *testing.T) {
 	var params = mandatoryRequestParams(testargs)
 	if params["apiKey"] != testargs.ApiKey || params["name"] != testargs.Name {
@@ -38,9 +52,25 @@ func TestCreateErrorResponse(t *testing.T) {
 	json := `{"code":10, "error": "test error"}`
 	errorResp, err := createErrorResponse([]byte(json))
 	if err != nil {
-		t.Errorf(err.Error())
+		t.Error(err)
 	}
 	if errorResp.Code != 10 || errorResp.Message != "test error" {
 		t.Errorf("Error [%+v] does not correspond to json [%s]", errorResp, json)
 	}
 }
+
+//startTestServer points the client to a local server that answers every request with 200 and records the request paths
+func startTestServer() (*httptest.Server, *[]string) {
+	paths := &[]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*paths = append(*paths, r.URL.Path)
+		w.Write([]byte("{}"))
+	}))
+	SetAPIURL(server.URL)
+	return server, paths
+}
+
+func stopTestServer(server *httptest.Server) {
+	server.Close()
+	SetAPIURL("https://api.opsgenie.com")
+}
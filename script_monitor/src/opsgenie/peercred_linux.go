@@ -0,0 +1,35 @@
+//go:build linux
+// +build linux
+
+package opsgenie
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"syscall"
+)
+
+//peerUID returns the uid of the process on the other side of a unix socket
+func peerUID(conn net.Conn) (string, error) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return "", fmt.Errorf("not a unix socket")
+	}
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return "", err
+	}
+	var cred *syscall.Ucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err != nil {
+		return "", err
+	}
+	if credErr != nil {
+		return "", credErr
+	}
+	return strconv.Itoa(int(cred.Uid)), nil
+}
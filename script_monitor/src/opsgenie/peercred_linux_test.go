@@ -0,0 +1,48 @@
+package opsgenie
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRelayAllowOverUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "relay.sock")
+	listener, err := relayListener("unix:" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := testRelay(func(args OpsArgs) error { return nil })
+	r.allow["secret"] = []string{strconv.Itoa(os.Getuid())}
+	r.allow["backup"] = []string{"nobody-at-all"}
+	server := &http.Server{Handler: r, ConnContext: withConn}
+	go server.Serve(listener)
+	defer server.Close()
+
+	client := &http.Client{Timeout: time.Second, Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return net.Dial("unix", path)
+		},
+	}}
+	for name, code := range map[string]int{"secret": http.StatusOK, "backup": http.StatusForbidden} {
+		resp, err := client.Post("http://relay/ping/"+name, "text/plain", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != code {
+			t.Errorf("Ping of [%s] answered [%d] but should be [%d]", name, resp.StatusCode, code)
+		}
+	}
+}
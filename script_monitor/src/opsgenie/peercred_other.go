@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package opsgenie
+
+import (
+	"fmt"
+	"net"
+)
+
+//peerUID is only supported on linux
+func peerUID(conn net.Conn) (string, error) {
+	return "", fmt.Errorf("peer credentials are only supported on linux")
+}
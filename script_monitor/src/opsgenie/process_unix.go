@@ -0,0 +1,36 @@
+//go:build !windows
+// +build !windows
+
+package opsgenie
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+//newProcessGroup starts the command in its own process group, so the processes it starts are stopped with it. That group
+//is in the background, reading from the terminal would stop it with SIGTTIN, so it does not get the terminal as stdin.
+func newProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if cmd.Stdin == os.Stdin && isTerminal(os.Stdin) {
+		cmd.Stdin = nil
+	}
+}
+
+//signalCommand sends the signal to the process group of the command
+func signalCommand(cmd *exec.Cmd, sig os.Signal) error {
+	unixSignal, ok := sig.(syscall.Signal)
+	if !ok {
+		return cmd.Process.Signal(sig)
+	}
+	return syscall.Kill(-cmd.Process.Pid, unixSignal)
+}
+
+func terminateCommand(cmd *exec.Cmd) error {
+	return signalCommand(cmd, syscall.SIGTERM)
+}
+
+func killCommand(cmd *exec.Cmd) error {
+	return signalCommand(cmd, syscall.SIGKILL)
+}
@@ -0,0 +1,27 @@
+//go:build windows
+// +build windows
+
+package opsgenie
+
+import (
+	"os"
+	"os/exec"
+)
+
+//newProcessGroup does nothing on windows, the command shares the console so it gets Ctrl-C itself
+func newProcessGroup(cmd *exec.Cmd) {
+}
+
+//signalCommand does nothing on windows, the console already delivered the signal to the command
+func signalCommand(cmd *exec.Cmd, sig os.Signal) error {
+	return nil
+}
+
+//terminateCommand kills the command, windows can't ask a process to stop
+func terminateCommand(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+func killCommand(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
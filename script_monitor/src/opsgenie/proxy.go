@@ -0,0 +1,73 @@
+package opsgenie
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/codegangsta/cli"
+)
+
+var proxyURL *url.URL
+var noProxy []string
+
+//SetProxy makes all requests use the given http, https or socks5 proxy instead of the proxy from the environment,
+//credentials for basic auth can be part of the url. noProxy is a comma separated list of hosts and domains that
+//are requested directly, * bypasses the proxy for all hosts.
+func SetProxy(proxy string, noProxyHosts string) error {
+	proxyURL = nil
+	if proxy != "" {
+		parsed, err := url.Parse(proxy)
+		if err != nil {
+			return err
+		}
+		if parsed.Scheme != "http" && parsed.Scheme != "https" && parsed.Scheme != "socks5" {
+			return fmt.Errorf("[proxy] scheme can only be one of the following: http, https or socks5")
+		}
+		proxyURL = parsed
+	}
+	noProxy = nil
+	for _, host := range strings.Split(noProxyHosts, ",") {
+		host = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(host)), ".")
+		if host != "" {
+			noProxy = append(noProxy, host)
+		}
+	}
+	httpTransport.CloseIdleConnections()
+	return nil
+}
+
+//ConfigureClient sets up the HTTP client from the flags or the config file, it is meant to be used from the application Before func
+func ConfigureClient(c *cli.Context) error {
+	config := &Config{}
+	if c.GlobalString("config") != "" {
+		var err error
+		config, err = loadConfig(c.GlobalString("config"))
+		if err != nil {
+			return err
+		}
+	}
+	SetDebugHTTP(c.GlobalBool("debugHttp"))
+	SetHistory(globalStringArg(c, "stateDir", config.StateDir), c.GlobalDuration("historyRetention"))
+	err := SetProxy(globalStringArg(c, "proxy", config.Proxy), globalStringArg(c, "noProxy", config.NoProxy))
+	if err != nil {
+		return err
+	}
+	resumeSnoozes(globalStringArg(c, "stateDir", config.StateDir), globalStringArg(c, "apiKey", config.ApiKey), time.Now())
+	return nil
+}
+
+func proxyForRequest(request *http.Request) (*url.URL, error) {
+	host := strings.ToLower(request.URL.Hostname())
+	for _, bypass := range noProxy {
+		if bypass == "*" || host == bypass || strings.HasSuffix(host, "."+bypass) {
+			return nil, nil
+		}
+	}
+	if proxyURL != nil {
+		return proxyURL, nil
+	}
+	return http.ProxyFromEnvironment(request)
+}
@@ -0,0 +1,187 @@
+package opsgenie
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/codegangsta/cli"
+)
+
+const patternMandatory = "[pattern] is mandatory, at least one pattern limits which heartbeats are pruned"
+
+//pruneCandidate is a heartbeat that has not been pinged for the given number of days. OpsGenie does not tell when a
+//heartbeat was disabled, so a disabled heartbeat is a candidate when it was last pinged before that.
+type pruneCandidate struct {
+	Heartbeat Heartbeat
+	Reason    string
+}
+
+//pruneResult is an entry of the JSON report, the action is disable or delete, or skipped when the prune was not confirmed
+type pruneResult struct {
+	Name          string `json:"name"`
+	Reason        string `json:"reason"`
+	LastHeartbeat string `json:"lastHeartbeat,omitempty"`
+	Action        string `json:"action"`
+	Success       bool   `json:"success"`
+	Error         string `json:"error,omitempty"`
+}
+
+func lastHeartbeatTime(heartbeat Heartbeat) time.Time {
+	if heartbeat.LastHeartbeat <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, heartbeat.LastHeartbeat*int64(time.Millisecond))
+}
+
+//staleHeartbeats returns the heartbeats matching the patterns that were not pinged for days, already disabled
+//heartbeats are only returned when includeDisabled is true. OpsGenie does not tell when a heartbeat was created, so
+//heartbeats that were never pinged may be new and are only returned when includeNeverPinged is true.
+func staleHeartbeats(heartbeats []Heartbeat, patterns []string, days int, now time.Time, includeDisabled bool, includeNeverPinged bool) ([]pruneCandidate, error) {
+	cutoff := now.Add(-time.Duration(days) * 24 * time.Hour)
+	var candidates []pruneCandidate
+	for _, heartbeat := range heartbeats {
+		matched, err := matchesAny(heartbeat.Name, patterns)
+		if err != nil {
+			return nil, err
+		}
+		last := lastHeartbeatTime(heartbeat)
+		if !matched || last.After(cutoff) || (last.IsZero() && !includeNeverPinged) {
+			continue
+		}
+		if !heartbeat.Enabled {
+			if includeDisabled {
+				candidates = append(candidates, pruneCandidate{heartbeat, fmt.Sprintf("disabled and not pinged for %d days", days)})
+			}
+			continue
+		}
+		candidates = append(candidates, pruneCandidate{heartbeat, fmt.Sprintf("not pinged for %d days", days)})
+	}
+	return candidates, nil
+}
+
+func printPruneTable(w io.Writer, candidates []pruneCandidate) {
+	fmt.Fprintf(w, "%-40s %-8s %-25s %s\n", "NAME", "ENABLED", "LAST HEARTBEAT", "REASON")
+	for _, candidate := range candidates {
+		last := "never"
+		if t := lastHeartbeatTime(candidate.Heartbeat); !t.IsZero() {
+			last = t.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%-40s %-8t %-25s %s\n", candidate.Heartbeat.Name, candidate.Heartbeat.Enabled, last, candidate.Reason)
+	}
+}
+
+func confirm(r io.Reader, w io.Writer, question string) bool {
+	fmt.Fprintf(w, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(r).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func pruneCandidates(apiKey string, candidates []pruneCandidate, action string) []pruneResult {
+	var results []pruneResult
+	for _, candidate := range candidates {
+		args := OpsArgs{ApiKey: apiKey, Name: candidate.Heartbeat.Name}
+		var err error
+		if action == "delete" {
+			err = deleteHeartbeat(args)
+		} else {
+			err = disableHeartbeat(args)
+		}
+		result := pruneResult{Name: args.Name, Reason: candidate.Reason, Action: action, Success: err == nil}
+		if t := lastHeartbeatTime(candidate.Heartbeat); !t.IsZero() {
+			result.LastHeartbeat = t.Format(time.RFC3339)
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+//skippedCandidates are the results when the prune was not confirmed
+func skippedCandidates(candidates []pruneCandidate) []pruneResult {
+	results := []pruneResult{}
+	for _, candidate := range candidates {
+		result := pruneResult{Name: candidate.Heartbeat.Name, Reason: candidate.Reason, Action: "skipped"}
+		if t := lastHeartbeatTime(candidate.Heartbeat); !t.IsZero() {
+			result.LastHeartbeat = t.Format(time.RFC3339)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+//writePruneReport writes the results as JSON, an empty list when nothing was found
+func writePruneReport(path string, results []pruneResult) error {
+	if path == "" {
+		return nil
+	}
+	if results == nil {
+		results = []pruneResult{}
+	}
+	content, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(content, '\n'), 0644)
+}
+
+func pruneHeartbeats(c *cli.Context) {
+	if len(c.StringSlice("pattern")) == 0 {
+		logAndExit(patternMandatory)
+		return
+	}
+	apiKey, err := apiKeyArg(c)
+	if err != nil {
+		logAndExit(err.Error())
+		return
+	}
+	heartbeats, err := listHeartbeats(apiKey)
+	if err != nil {
+		logAndExit(err.Error())
+		return
+	}
+	action := "disable"
+	if c.Bool("delete") {
+		action = "delete"
+	}
+	candidates, err := staleHeartbeats(heartbeats, c.StringSlice("pattern"), c.Int("days"), time.Now(), action == "delete", c.Bool("neverPinged"))
+	if err != nil {
+		logAndExit(err.Error())
+		return
+	}
+	if len(candidates) == 0 {
+		fmt.Println("No stale heartbeats found")
+		if err := writePruneReport(c.String("report"), nil); err != nil {
+			logAndExit(err.Error())
+		}
+		return
+	}
+	printPruneTable(os.Stdout, candidates)
+	if !c.Bool("yes") && !confirm(os.Stdin, os.Stdout, fmt.Sprintf("%s %d heartbeats?", map[string]string{"disable": "Disable", "delete": "Delete"}[action], len(candidates))) {
+		if err := writePruneReport(c.String("report"), skippedCandidates(candidates)); err != nil {
+			logAndExit(err.Error())
+		}
+		return
+	}
+	results := pruneCandidates(apiKey, candidates, action)
+	failed := false
+	for _, result := range results {
+		failed = failed || !result.Success
+	}
+	if err := writePruneReport(c.String("report"), results); err != nil {
+		log.Error(err)
+		failed = true
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
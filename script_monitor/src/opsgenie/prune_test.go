@@ -0,0 +1,108 @@
+package opsgenie
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codegangsta/cli"
+)
+
+func TestStaleHeartbeats(t *testing.T) {
+	now := time.Now()
+	daysAgo := func(days int) int64 {
+		return now.Add(-time.Duration(days)*24*time.Hour).UnixNano() / int64(time.Millisecond)
+	}
+	heartbeats := []Heartbeat{
+		{Name: "web-active", Enabled: true, LastHeartbeat: daysAgo(1)},
+		{Name: "web-stale", Enabled: true, LastHeartbeat: daysAgo(40)},
+		{Name: "web-never", Enabled: true},
+		{Name: "web-disabled", Enabled: false, LastHeartbeat: daysAgo(40)},
+		{Name: "db-stale", Enabled: true, LastHeartbeat: daysAgo(40)},
+	}
+
+	candidates, err := staleHeartbeats(heartbeats, []string{"web-*"}, 30, now, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, candidate := range candidates {
+		names = append(names, candidate.Heartbeat.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"web-stale"}) {
+		t.Errorf("Stale heartbeats are [%v]", names)
+	}
+
+	candidates, _ = staleHeartbeats(heartbeats, []string{"web-*"}, 30, now, false, true)
+	if len(candidates) != 2 || candidates[1].Heartbeat.Name != "web-never" {
+		t.Errorf("Stale heartbeats including never pinged are [%+v]", candidates)
+	}
+
+	candidates, _ = staleHeartbeats(heartbeats, []string{"web-*"}, 30, now, true, true)
+	if len(candidates) != 3 || candidates[2].Heartbeat.Name != "web-disabled" || !strings.Contains(candidates[2].Reason, "disabled") {
+		t.Errorf("Stale heartbeats including disabled are [%+v]", candidates)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	var out bytes.Buffer
+	if !confirm(strings.NewReader("y\n"), &out, "Disable?") || out.String() != "Disable? [y/N] " {
+		t.Errorf("Confirmation with y not accepted [%s]", out.String())
+	}
+	if confirm(strings.NewReader("\n"), &out, "Disable?") {
+		t.Errorf("Empty answer accepted")
+	}
+}
+
+func TestPruneCandidates(t *testing.T) {
+	server, paths := startTestServer()
+	defer stopTestServer(server)
+
+	results := pruneCandidates("key", []pruneCandidate{{Heartbeat{Name: "old"}, "not pinged for 30 days"}}, "delete")
+	if len(results) != 1 || !results[0].Success || results[0].Action != "delete" || len(*paths) != 1 {
+		t.Errorf("Prune results are [%+v] with requests [%v]", results, *paths)
+	}
+}
+
+func TestPrunePatternMandatory(t *testing.T) {
+	defer func(original func(string)) { logAndExit = original }(logAndExit)
+	var exitMsg string
+	logAndExit = func(msg string) {
+		exitMsg = msg
+	}
+	set := flag.NewFlagSet("prune", 0)
+	set.Var(&cli.StringSlice{}, "pattern", "")
+	pruneHeartbeats(cli.NewContext(nil, set, flag.NewFlagSet("global", 0)))
+	if exitMsg != patternMandatory {
+		t.Errorf("Prune without pattern exited with [%s]", exitMsg)
+	}
+}
+
+func TestWritePruneReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "report.json")
+
+	if err := writePruneReport(path, nil); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := ioutil.ReadFile(path); string(content) != "[]\n" {
+		t.Errorf("Report without stale heartbeats is [%s]", content)
+	}
+	skipped := skippedCandidates([]pruneCandidate{{Heartbeat{Name: "old"}, "not pinged for 30 days"}})
+	if err := writePruneReport(path, skipped); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := ioutil.ReadFile(path); !strings.Contains(string(content), `"action": "skipped"`) || !strings.Contains(string(content), `"name": "old"`) {
+		t.Errorf("Report of a declined prune is [%s]", content)
+	}
+}
@@ -0,0 +1,270 @@
+package opsgenie
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/user"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/codegangsta/cli"
+)
+
+var relayFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "listen",
+		Value: "127.0.0.1:8625",
+		Usage: "Loopback address or unix:/path/to/socket to listen on",
+	},
+	cli.DurationFlag{
+		Name:  "rateLimit",
+		Value: 10 * time.Second,
+		Usage: "Minimum time between two forwarded pings of the same heartbeat",
+	},
+	cli.IntFlag{
+		Name:  "retries",
+		Value: 3,
+		Usage: "Number of times a failed ping is retried",
+	},
+	cli.DurationFlag{
+		Name:  "retryDelay",
+		Value: time.Second,
+		Usage: "Delay before the first retry, doubled for every next retry",
+	},
+}
+
+type connKey struct{}
+
+//withConn makes the connection available to the handler for the peer credentials
+func withConn(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, connKey{}, conn)
+}
+
+//relay forwards pings from local scripts to the configured heartbeats, so the scripts do not need the api key
+type relay struct {
+	heartbeats map[string]OpsArgs
+	allow      map[string][]string
+	rateLimit  time.Duration
+	retries    int
+	retryDelay time.Duration
+	send       func(OpsArgs) error
+
+	mu       sync.Mutex
+	lastPing map[string]time.Time
+	inFlight map[string]bool
+}
+
+func newRelay(config *Config, apiKey string) (*relay, error) {
+	r := &relay{
+		heartbeats: make(map[string]OpsArgs),
+		allow:      make(map[string][]string),
+		lastPing:   make(map[string]time.Time),
+		inFlight:   make(map[string]bool),
+		send:       sendHeartbeat,
+	}
+	for _, heartbeat := range config.Heartbeats {
+		if heartbeat.templateErr != nil {
+			log.Warn(heartbeat.templateErr)
+			continue
+		}
+		args, err := config.opsArgs(heartbeat.Name)
+		if err != nil {
+			return nil, err
+		}
+		args.ApiKey = apiKey
+		r.heartbeats[heartbeat.Name] = args
+		r.allow[heartbeat.Name] = heartbeat.RelayAllow
+	}
+	return r, nil
+}
+
+func (r *relay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	name := strings.TrimPrefix(req.URL.Path, "/ping/")
+	if name == req.URL.Path || name == "" {
+		http.NotFound(w, req)
+		return
+	}
+	if req.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	args, ok := r.heartbeats[name]
+	if !ok {
+		http.Error(w, fmt.Sprintf("Heartbeat [%s] is not configured", name), http.StatusNotFound)
+		return
+	}
+	fields := requestFields("relay", name)
+	conn, _ := req.Context().Value(connKey{}).(net.Conn)
+	if err := localRequest(req, conn); err != nil {
+		log.WithFields(fields).Warn(err)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err := r.allowed(name, conn); err != nil {
+		log.WithFields(fields).Warn(err)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	now := time.Now()
+	if snoozed(args, now) || inMaintenance(args.Maintenance, now) {
+		log.WithFields(fields).Debugf("Heartbeat [%s] is snoozed or in a maintenance window, not sending", name)
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, "Heartbeat [%s] is snoozed or in a maintenance window, not sent\n", name)
+		return
+	}
+	if !r.reservePing(name, now) {
+		http.Error(w, fmt.Sprintf("Heartbeat [%s] was pinged less than %s ago", name, r.rateLimit), http.StatusTooManyRequests)
+		return
+	}
+	err := r.forward(args)
+	r.releasePing(name, err == nil, time.Now())
+	if err != nil {
+		log.WithFields(fields).Error(err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	fmt.Fprintf(w, "Sent heartbeat [%s]\n", name)
+}
+
+//localRequest rejects requests from a browser, a web page could otherwise ping the relay with a cross-site request or
+//through a dns name that resolves to the loopback address. Browsers can't connect to a unix socket so the host is not checked there.
+func localRequest(req *http.Request, conn net.Conn) error {
+	if req.Header.Get("Origin") != "" {
+		return fmt.Errorf("Requests with an Origin header are not allowed")
+	}
+	if _, unix := conn.(*net.UnixConn); unix {
+		return nil
+	}
+	host := req.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("Host [%s] is not a loopback address", req.Host)
+	}
+	return nil
+}
+
+//allowed checks the local user of the connection against the allow-list of the heartbeat, users can only be known
+//on a unix socket so a heartbeat with an allow-list can not be pinged over http
+func (r *relay) allowed(name string, conn net.Conn) error {
+	allow := r.allow[name]
+	if len(allow) == 0 {
+		return nil
+	}
+	uid, err := peerUID(conn)
+	if err != nil {
+		return fmt.Errorf("Heartbeat [%s] has an allow-list and the user is unknown: %s", name, err)
+	}
+	names := []string{uid}
+	if u, err := user.LookupId(uid); err == nil {
+		names = append(names, u.Username)
+	}
+	for _, allowed := range allow {
+		for _, n := range names {
+			if allowed == n {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("User [%s] is not allowed to ping heartbeat [%s]", strings.Join(names, "/"), name)
+}
+
+//reservePing returns false when the heartbeat was sent within the rate limit or is being sent, otherwise it marks the
+//heartbeat as being sent so concurrent pings are refused while forward retries
+func (r *relay) reservePing(name string, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if last, ok := r.lastPing[name]; r.inFlight[name] || (ok && now.Sub(last) < r.rateLimit) {
+		return false
+	}
+	r.inFlight[name] = true
+	return true
+}
+
+//releasePing only counts a ping that was sent for the rate limit, so a script can retry a ping that failed
+func (r *relay) releasePing(name string, sent bool, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.inFlight, name)
+	if sent {
+		r.lastPing[name] = now
+	}
+}
+
+//forward sends the heartbeat and retries with a doubling delay, errors returned by OpsGenie are not retried
+func (r *relay) forward(args OpsArgs) error {
+	return Retry(r.send, args, r.retries, r.retryDelay)
+}
+
+//relayListener listens on a unix socket for unix:path and otherwise on a tcp address that must be a loopback address
+func relayListener(address string) (net.Listener, error) {
+	if strings.HasPrefix(address, "unix:") {
+		path := strings.TrimPrefix(address, "unix:")
+		//Only a socket left behind by a previous relay is removed, never another file
+		if info, err := os.Lstat(path); err == nil {
+			if info.Mode()&os.ModeSocket == 0 {
+				return nil, fmt.Errorf("[listen] path [%s] exists and is not a socket", path)
+			}
+			os.Remove(path)
+		}
+		listener, err := net.Listen("unix", path)
+		if err != nil {
+			return nil, err
+		}
+		//Every local user may connect, the allow-lists decide who may ping what
+		return listener, os.Chmod(path, 0666)
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range ips {
+		if !ip.IsLoopback() {
+			return nil, fmt.Errorf("[listen] address [%s] is not a loopback address, the relay does not ask for credentials", address)
+		}
+	}
+	return net.Listen("tcp", address)
+}
+
+func runRelay(c *cli.Context) {
+	if c.GlobalString("config") == "" {
+		logAndExit(configMandatory)
+		return
+	}
+	config, err := loadConfig(c.GlobalString("config"))
+	if err != nil {
+		logAndExit(err.Error())
+		return
+	}
+	apiKey := globalStringArg(c, "apiKey", config.ApiKey)
+	if apiKey == "" {
+		logAndExit(mandatoryFlags)
+		return
+	}
+	config.StateDir = globalStringArg(c, "stateDir", config.StateDir)
+	r, err := newRelay(config, apiKey)
+	if err != nil {
+		logAndExit(err.Error())
+		return
+	}
+	r.rateLimit, r.retries, r.retryDelay = c.Duration("rateLimit"), c.Int("retries"), c.Duration("retryDelay")
+	listener, err := relayListener(c.String("listen"))
+	if err != nil {
+		logAndExit(err.Error())
+		return
+	}
+	server := &http.Server{Handler: r, ConnContext: withConn}
+	log.Infof("Relaying pings for %d heartbeats on [%s]", len(r.heartbeats), c.String("listen"))
+	log.Fatal(server.Serve(listener))
+}
@@ -0,0 +1,209 @@
+package opsgenie
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testRelay(send func(OpsArgs) error) *relay {
+	config := &Config{Heartbeats: []HeartbeatConfig{{Name: "backup"}, {Name: "secret", RelayAllow: []string{"root"}}}}
+	r, _ := newRelay(config, "relayKey")
+	r.rateLimit = time.Minute
+	r.send = send
+	return r
+}
+
+func relayRequest(r *relay, method string, path string) int {
+	req := httptest.NewRequest(method, path, nil)
+	req.Host = "127.0.0.1:8625"
+	return serveRelay(r, req)
+}
+
+func serveRelay(r *relay, req *http.Request) int {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestRelayPing(t *testing.T) {
+	var sent []OpsArgs
+	r := testRelay(func(args OpsArgs) error {
+		sent = append(sent, args)
+		return nil
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+	}{
+		{"POST", "/ping/backup", http.StatusOK},
+		{"POST", "/ping/backup", http.StatusTooManyRequests},
+		{"GET", "/ping/backup", http.StatusMethodNotAllowed},
+		{"POST", "/ping/unknown", http.StatusNotFound},
+		{"POST", "/other", http.StatusNotFound},
+		{"POST", "/ping/secret", http.StatusForbidden},
+	}
+	for _, test := range tests {
+		if code := relayRequest(r, test.method, test.path); code != test.code {
+			t.Errorf("%s %s answered [%d] but should be [%d]", test.method, test.path, code, test.code)
+		}
+	}
+	if len(sent) != 1 || sent[0].Name != "backup" || sent[0].ApiKey != "relayKey" {
+		t.Errorf("Sent heartbeats not correct [%+v]", sent)
+	}
+}
+
+func TestRelayRejectsBrowsers(t *testing.T) {
+	r := testRelay(func(args OpsArgs) error {
+		return nil
+	})
+	req := httptest.NewRequest("POST", "/ping/backup", nil)
+	req.Host = "localhost:8625"
+	req.Header.Set("Origin", "http://example.com")
+	if code := serveRelay(r, req); code != http.StatusForbidden {
+		t.Errorf("Request with an Origin header answered [%d]", code)
+	}
+	req = httptest.NewRequest("POST", "/ping/backup", nil)
+	req.Host = "rebound.example.com:8625"
+	if code := serveRelay(r, req); code != http.StatusForbidden {
+		t.Errorf("Request with a non loopback Host answered [%d]", code)
+	}
+	req = httptest.NewRequest("POST", "/ping/backup", nil)
+	req.Host = "localhost:8625"
+	if code := serveRelay(r, req); code != http.StatusOK {
+		t.Errorf("Request to localhost answered [%d]", code)
+	}
+}
+
+func TestRelayFailedPingNotRateLimited(t *testing.T) {
+	failing := true
+	r := testRelay(func(args OpsArgs) error {
+		if failing {
+			return ErrorResponse{Code: 5, Message: "Temporary failure"}
+		}
+		return nil
+	})
+	if code := relayRequest(r, "POST", "/ping/backup"); code != http.StatusBadGateway {
+		t.Errorf("Failed ping answered [%d]", code)
+	}
+	failing = false
+	if code := relayRequest(r, "POST", "/ping/backup"); code != http.StatusOK {
+		t.Errorf("Retry of a failed ping answered [%d]", code)
+	}
+}
+
+func TestRelayConcurrentPings(t *testing.T) {
+	release := make(chan bool)
+	var sent int32
+	r := testRelay(func(args OpsArgs) error {
+		atomic.AddInt32(&sent, 1)
+		<-release
+		return nil
+	})
+	codes := make(chan int, 5)
+	for i := 0; i < 5; i++ {
+		go func() {
+			codes <- relayRequest(r, "POST", "/ping/backup")
+		}()
+	}
+	refused := 0
+	for i := 0; i < 4; i++ {
+		if <-codes == http.StatusTooManyRequests {
+			refused++
+		}
+	}
+	close(release)
+	if code := <-codes; code != http.StatusOK || refused != 4 || atomic.LoadInt32(&sent) != 1 {
+		t.Errorf("Concurrent pings sent [%d] times, refused [%d] and the last answered [%d]", sent, refused, code)
+	}
+}
+
+func TestRelaySnoozedAndMaintenance(t *testing.T) {
+	stateDir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(stateDir)
+	config := &Config{StateDir: stateDir, Heartbeats: []HeartbeatConfig{
+		{Name: "snoozed"},
+		{Name: "maintenance", Maintenance: []MaintenanceWindow{{Schedule: "* * * * *", Duration: "1h"}}},
+	}}
+	r, _ := newRelay(config, "relayKey")
+	sent := 0
+	r.send = func(args OpsArgs) error {
+		sent++
+		return nil
+	}
+	if err := writeState(stateFile(stateDir, "snooze", "snoozed"), snoozeState{Name: "snoozed", Until: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"snoozed", "maintenance"} {
+		if code := relayRequest(r, "POST", "/ping/"+name); code != http.StatusAccepted || sent != 0 {
+			t.Errorf("Ping of heartbeat [%s] answered [%d] and sent [%d]", name, code, sent)
+		}
+	}
+}
+
+func TestRelayRetries(t *testing.T) {
+	attempts := 0
+	r := testRelay(func(args OpsArgs) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	r.retries, r.retryDelay = 3, time.Millisecond
+	if code := relayRequest(r, "POST", "/ping/backup"); code != http.StatusOK || attempts != 3 {
+		t.Errorf("Relay answered [%d] after [%d] attempts", code, attempts)
+	}
+
+	attempts = 0
+	r = testRelay(func(args OpsArgs) error {
+		attempts++
+		return ErrorResponse{Code: 3, Message: "Invalid apiKey"}
+	})
+	r.retries, r.retryDelay = 3, time.Millisecond
+	if code := relayRequest(r, "POST", "/ping/backup"); code != http.StatusBadGateway || attempts != 1 {
+		t.Errorf("Rejected ping answered [%d] after [%d] attempts", code, attempts)
+	}
+}
+
+func TestRelayListener(t *testing.T) {
+	if _, err := relayListener("8.8.8.8:8625"); err == nil {
+		t.Error("Non loopback address accepted")
+	}
+	dir, err := ioutil.TempDir("", "relay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "relay.sock")
+	listener, err := relayListener("unix:" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0666 {
+		t.Errorf("Socket not accessible for local users [%v] [%v]", info, err)
+	}
+
+	file := filepath.Join(dir, "relay.conf")
+	if err := ioutil.WriteFile(file, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := relayListener("unix:" + file); err == nil {
+		t.Error("Listening on a path that is not a socket")
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("File at the socket path removed [%v]", err)
+	}
+}
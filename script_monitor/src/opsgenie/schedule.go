@@ -0,0 +1,163 @@
+package opsgenie
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var monthNames = map[string]int{"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6, "JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12}
+var dayNames = map[string]int{"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6}
+
+//cronSchedule is a parsed five field cron expression: minute, hour, day of month, month and day of week
+type cronSchedule struct {
+	minute     map[int]bool
+	hour       map[int]bool
+	dayOfMonth map[int]bool
+	month      map[int]bool
+	dayOfWeek  map[int]bool
+	//as in cron, when both day fields are restricted a day matches if either field matches
+	dayOfMonthAll bool
+	dayOfWeekAll  bool
+	//a schedule with a wildcard minute or hour also fires in the hour repeated when DST ends, like cron
+	repeats  bool
+	location *time.Location
+}
+
+func parseCron(expression string, timeZone string) (*cronSchedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("Cron expression [%s] should have 5 fields: minute hour day-of-month month day-of-week", expression)
+	}
+	var err error
+	location := time.Local
+	if timeZone != "" {
+		location, err = time.LoadLocation(timeZone)
+		if err != nil {
+			return nil, err
+		}
+	}
+	schedule := &cronSchedule{location: location}
+	if schedule.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, err
+	}
+	if schedule.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, err
+	}
+	if schedule.dayOfMonth, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, err
+	}
+	if schedule.month, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, err
+	}
+	if schedule.dayOfWeek, err = parseCronField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, err
+	}
+	if schedule.dayOfWeek[7] {
+		schedule.dayOfWeek[0] = true
+	}
+	schedule.dayOfMonthAll = fields[2] == "*" || fields[2] == "?"
+	schedule.dayOfWeekAll = fields[4] == "*" || fields[4] == "?"
+	schedule.repeats = strings.Contains(fields[0], "*") || strings.Contains(fields[1], "*")
+	return schedule, nil
+}
+
+func parseCronField(field string, min int, max int, names map[string]int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step < 1 {
+				return nil, fmt.Errorf("Cron field [%s] has an invalid step", field)
+			}
+			part = part[:i]
+		}
+		start, end := min, max
+		if part != "*" && part != "?" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if start, err = parseCronValue(bounds[0], names); err != nil {
+				return nil, err
+			}
+			end = start
+			if len(bounds) == 2 {
+				if end, err = parseCronValue(bounds[1], names); err != nil {
+					return nil, err
+				}
+			} else if step > 1 {
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return nil, fmt.Errorf("Cron field [%s] is out of range %d-%d", field, min, max)
+		}
+		for v := start; v <= end; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+func parseCronValue(value string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToUpper(value)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("Cron value [%s] is not a number", value)
+	}
+	return v, nil
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dayOfMonth := s.dayOfMonth[t.Day()]
+	dayOfWeek := s.dayOfWeek[int(t.Weekday())]
+	if s.dayOfMonthAll || s.dayOfWeekAll {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
+
+//next returns the first fire time after t. It steps through absolute time and matches the wall clock of the schedule's
+//location, so a time skipped by a DST change does not fire and a fixed time repeated by a DST change fires once,
+//while a schedule with a wildcard minute or hour keeps firing through the repeated hour.
+func (s *cronSchedule) next(t time.Time) time.Time {
+	c := t.In(s.location).Truncate(time.Minute).Add(time.Minute)
+	//enough iterations to search more than four years ahead, for schedules like 29 February
+	for i := 0; i < 100000; i++ {
+		year, month, day := c.Date()
+		var skip time.Time
+		switch {
+		case !s.month[int(month)]:
+			skip = time.Date(year, month+1, 1, 0, 0, 0, 0, s.location)
+		case !s.dayMatches(c):
+			skip = time.Date(year, month, day+1, 0, 0, 0, 0, s.location)
+		case !s.hour[c.Hour()]:
+			skip = c.Add(time.Duration(60-c.Minute()) * time.Minute)
+		case !s.minute[c.Minute()] || (!s.repeats && repeatedWallClock(c)):
+			skip = c.Add(time.Minute)
+		default:
+			return c
+		}
+		//time.Date may resolve a wall clock time in a DST change to an earlier instant
+		if !skip.After(c) {
+			skip = c.Add(time.Minute)
+		}
+		c = skip.In(s.location)
+	}
+	return time.Time{}
+}
+
+//repeatedWallClock reports whether the wall clock time of t already occurred earlier, because DST ended in between
+func repeatedWallClock(t time.Time) bool {
+	_, offset := t.Zone()
+	_, before := t.Add(-3 * time.Hour).Zone()
+	if before <= offset {
+		return false
+	}
+	_, twin := t.Add(-time.Duration(before-offset) * time.Second).Zone()
+	return twin == before
+}
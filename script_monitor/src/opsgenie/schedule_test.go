@@ -0,0 +1,88 @@
+package opsgenie
+
+import (
+	"testing"
+	"time"
+)
+
+func mustParseCron(t *testing.T, expression string, timeZone string) *cronSchedule {
+	schedule, err := parseCron(expression, timeZone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schedule
+}
+
+func TestParseCronWrong(t *testing.T) {
+	for _, expression := range []string{"* * * *", "60 * * * *", "* 5-2 * * *", "*/0 * * * *", "* * * FOO *"} {
+		if _, err := parseCron(expression, "UTC"); err == nil {
+			t.Errorf("Cron expression [%s] should not be valid", expression)
+		}
+	}
+	if _, err := parseCron("* * * * *", "Nowhere/Special"); err == nil {
+		t.Errorf("Unknown time zone should not be valid")
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		expression string
+		from       string
+		next       string
+	}{
+		{"*/15 * * * *", "2016-03-01T10:07:30Z", "2016-03-01T10:15:00Z"},
+		{"0 9-17 * * MON-FRI", "2016-03-04T17:30:00Z", "2016-03-07T09:00:00Z"},
+		{"30 2 1 * *", "2016-12-05T00:00:00Z", "2017-01-01T02:30:00Z"},
+		{"0 0 29 2 *", "2016-03-01T00:00:00Z", "2020-02-29T00:00:00Z"},
+		{"0 12 1 * SUN", "2016-03-01T13:00:00Z", "2016-03-06T12:00:00Z"},
+		{"0 0 * * 7", "2016-03-01T00:00:00Z", "2016-03-06T00:00:00Z"},
+	}
+	for _, test := range tests {
+		from, _ := time.Parse(time.RFC3339, test.from)
+		next := mustParseCron(t, test.expression, "UTC").next(from)
+		if next.Format(time.RFC3339) != test.next {
+			t.Errorf("Next of [%s] from [%s] is [%s] but should be [%s]", test.expression, test.from, next.Format(time.RFC3339), test.next)
+		}
+	}
+}
+
+func TestCronNextAcrossDST(t *testing.T) {
+	//Europe/Amsterdam moves from 02:00 to 03:00 on 27 March 2016 and from 03:00 to 02:00 on 30 October 2016
+	schedule := mustParseCron(t, "0 9 * * *", "Europe/Amsterdam")
+	from, _ := time.Parse(time.RFC3339, "2016-03-26T09:00:00+01:00")
+	next := schedule.next(from)
+	if next.Format(time.RFC3339) != "2016-03-27T09:00:00+02:00" || next.Sub(from) != 23*time.Hour {
+		t.Errorf("Next fire time across DST start is [%s]", next.Format(time.RFC3339))
+	}
+
+	schedule = mustParseCron(t, "30 2 * * *", "Europe/Amsterdam")
+	from, _ = time.Parse(time.RFC3339, "2016-03-26T03:00:00+01:00")
+	next = schedule.next(from)
+	if next.Format(time.RFC3339) != "2016-03-28T02:30:00+02:00" {
+		t.Errorf("Skipped time should not fire, next is [%s]", next.Format(time.RFC3339))
+	}
+
+	from, _ = time.Parse(time.RFC3339, "2016-10-30T01:00:00+02:00")
+	next = schedule.next(from)
+	if next.Format("2006-01-02T15:04") != "2016-10-30T02:30" {
+		t.Errorf("Repeated time should fire, next is [%s]", next.Format(time.RFC3339))
+	}
+	next = schedule.next(next)
+	if next.Format(time.RFC3339) != "2016-10-31T02:30:00+01:00" {
+		t.Errorf("Repeated time should fire once, next is [%s]", next.Format(time.RFC3339))
+	}
+}
+
+func TestCronNextAcrossDSTShortInterval(t *testing.T) {
+	schedule := mustParseCron(t, "*/5 * * * *", "Europe/Amsterdam")
+	for _, start := range []string{"2016-03-27T01:50:00+01:00", "2016-10-30T01:50:00+02:00"} {
+		from, _ := time.Parse(time.RFC3339, start)
+		for i := 0; i < 30; i++ {
+			next := schedule.next(from)
+			if next.Sub(from) != 5*time.Minute {
+				t.Fatalf("Next fire time after [%s] is [%s]", from.Format(time.RFC3339), next.Format(time.RFC3339))
+			}
+			from = next
+		}
+	}
+}
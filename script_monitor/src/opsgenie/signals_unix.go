@@ -0,0 +1,11 @@
+//go:build !windows
+// +build !windows
+
+package opsgenie
+
+import (
+	"os"
+	"syscall"
+)
+
+var forwardedSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGUSR1, syscall.SIGUSR2}
@@ -0,0 +1,12 @@
+//go:build windows
+// +build windows
+
+package opsgenie
+
+import (
+	"os"
+	"syscall"
+)
+
+//forwardedSignals are the only signals windows delivers, for Ctrl-C and closing the console
+var forwardedSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
@@ -0,0 +1,111 @@
+package opsgenie
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const snoozeWrong = "[for] should be a positive duration"
+const snoozeStateDirMandatory = "[stateDir] is mandatory, it keeps the pending re-enable also when [wait] is stopped"
+
+//snoozeState is the pending re-enable of a snoozed heartbeat, the apiKey comes from the flags or config when it is resumed
+type snoozeState struct {
+	Name  string    `json:"name"`
+	Until time.Time `json:"until"`
+}
+
+//snoozeHeartbeat disables the heartbeat and records when it has to be enabled again, with wait it waits and enables it
+//itself. The record is also kept with wait, so a later invocation enables the heartbeat when the wait is killed.
+func snoozeHeartbeat(args OpsArgs, duration time.Duration, wait bool) error {
+	if duration <= 0 {
+		return fmt.Errorf(snoozeWrong)
+	}
+	if args.StateDir == "" {
+		return fmt.Errorf(snoozeStateDirMandatory)
+	}
+	state := snoozeState{args.Name, time.Now().Add(duration)}
+	err := writeState(stateFile(args.StateDir, "snooze", args.Name), state)
+	if err != nil {
+		return err
+	}
+	err = disableHeartbeat(args)
+	if err != nil {
+		os.Remove(stateFile(args.StateDir, "snooze", args.Name))
+		return err
+	}
+	log.WithFields(requestFields("snooze", args.Name)).Infof("Snoozed heartbeat [%s] until %s", args.Name, state.Until.Format(time.RFC3339))
+	if !wait {
+		return nil
+	}
+	time.Sleep(state.Until.Sub(time.Now()))
+	return resumeSnooze(args.StateDir, args.ApiKey, state, true)
+}
+
+//resumeSnooze enables the heartbeat again, with send it also sends a heartbeat to reactivate it
+func resumeSnooze(stateDir string, apiKey string, state snoozeState, send bool) error {
+	args := OpsArgs{ApiKey: apiKey, Name: state.Name}
+	err := enableHeartbeat(args)
+	if err != nil {
+		return err
+	}
+	if stateDir != "" {
+		os.Remove(stateFile(stateDir, "snooze", state.Name))
+	}
+	if !send {
+		return nil
+	}
+	return sendHeartbeat(args)
+}
+
+//resumeSnoozes enables every snoozed heartbeat in stateDir whose snooze ended before now
+func resumeSnoozes(stateDir string, apiKey string, now time.Time) {
+	if stateDir == "" {
+		return
+	}
+	paths, _ := filepath.Glob(filepath.Join(stateDir, "snooze-*.json"))
+	for _, path := range paths {
+		state := snoozeState{}
+		err := readState(path, &state)
+		if err != nil {
+			log.WithFields(requestFields("snooze", "")).Errorf("Could not read snooze [%s]: %s", path, err)
+			continue
+		}
+		if now.Before(state.Until) {
+			continue
+		}
+		if apiKey == "" {
+			log.WithFields(requestFields("snooze", state.Name)).Warn("Snooze ended but there is no [apiKey] to re-enable the heartbeat")
+			continue
+		}
+		err = resumeSnooze(stateDir, apiKey, state, true)
+		if err != nil {
+			log.WithFields(requestFields("snooze", state.Name)).Errorf("Could not re-enable snoozed heartbeat: %s", err)
+		}
+	}
+}
+
+//snoozed reports whether the heartbeat is still snoozed, a snooze that ended is resumed without sending because the
+//loop sends right after
+func snoozed(args OpsArgs, now time.Time) bool {
+	if args.StateDir == "" {
+		return false
+	}
+	state := snoozeState{}
+	err := readState(stateFile(args.StateDir, "snooze", args.Name), &state)
+	if err != nil || state.Name == "" {
+		return false
+	}
+	if now.Before(state.Until) {
+		return true
+	}
+	err = resumeSnooze(args.StateDir, args.ApiKey, state, false)
+	if err != nil {
+		log.WithFields(requestFields("snooze", args.Name)).Errorf("Could not re-enable snoozed heartbeat: %s", err)
+		return true
+	}
+	return false
+}
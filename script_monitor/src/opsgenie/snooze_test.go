@@ -0,0 +1,115 @@
+package opsgenie
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnoozeAndResume(t *testing.T) {
+	server, paths := startTestServer()
+	defer stopTestServer(server)
+	stateDir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(stateDir)
+	args := testargs
+	args.StateDir = stateDir
+
+	err = snoozeHeartbeat(args, 45*time.Minute, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := ioutil.ReadFile(stateFile(stateDir, "snooze", args.Name))
+	if strings.Contains(string(content), args.ApiKey) {
+		t.Errorf("Snooze state contains the apiKey [%s]", content)
+	}
+	if !snoozed(args, time.Now()) {
+		t.Errorf("Heartbeat not snoozed")
+	}
+	if snoozed(args, time.Now().Add(time.Hour)) {
+		t.Errorf("Heartbeat still snoozed after the snooze ended")
+	}
+	expected := []string{"/v1/json/heartbeat/disable", "/v1/json/heartbeat/enable"}
+	if !reflect.DeepEqual(*paths, expected) {
+		t.Errorf("Requests are [%v] but should be [%v]", *paths, expected)
+	}
+}
+
+func TestResumeSnoozes(t *testing.T) {
+	server, paths := startTestServer()
+	defer stopTestServer(server)
+	stateDir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(stateDir)
+	writeState(stateFile(stateDir, "snooze", "ended"), snoozeState{"ended", time.Now().Add(-time.Minute)})
+	writeState(stateFile(stateDir, "snooze", "running"), snoozeState{"running", time.Now().Add(time.Hour)})
+
+	resumeSnoozes(stateDir, "", time.Now())
+	if len(*paths) != 0 {
+		t.Errorf("Snooze resumed without apiKey [%v]", *paths)
+	}
+	resumeSnoozes(stateDir, "key", time.Now())
+	expected := []string{"/v1/json/heartbeat/enable", "/v1/json/heartbeat/send"}
+	if !reflect.DeepEqual(*paths, expected) {
+		t.Errorf("Requests are [%v] but should be [%v]", *paths, expected)
+	}
+}
+
+func TestLoopTickWhileSnoozed(t *testing.T) {
+	server, paths := startTestServer()
+	defer stopTestServer(server)
+	stateDir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(stateDir)
+	args := testargs
+	args.StateDir = stateDir
+	writeState(stateFile(stateDir, "snooze", args.Name), snoozeState{args.Name, time.Now().Add(50 * time.Millisecond)})
+	tick := loopTick(args, nil, true)
+
+	tick()
+	if len(*paths) != 0 {
+		t.Errorf("Snoozed heartbeat started or sent [%v]", *paths)
+	}
+	time.Sleep(60 * time.Millisecond)
+	tick()
+	if countPaths(*paths, "/v1/json/heartbeat/send") != 1 || countPaths(*paths, "/v1/json/heartbeat/enable") != 1 {
+		t.Errorf("Resumed heartbeat should be enabled and sent once [%v]", *paths)
+	}
+}
+
+func TestSnoozeWait(t *testing.T) {
+	server, paths := startTestServer()
+	defer stopTestServer(server)
+
+	stateDir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(stateDir)
+	args := testargs
+	args.StateDir = stateDir
+
+	err = snoozeHeartbeat(args, 10*time.Millisecond, true)
+	expected := []string{"/v1/json/heartbeat/disable", "/v1/json/heartbeat/enable", "/v1/json/heartbeat/send"}
+	if err != nil || !reflect.DeepEqual(*paths, expected) {
+		t.Errorf("Requests are [%v] but should be [%v] [%v]", *paths, expected, err)
+	}
+}
+
+func TestSnoozeWrongArgs(t *testing.T) {
+	if snoozeHeartbeat(testargs, 0, true) == nil {
+		t.Errorf("Snooze without duration accepted")
+	}
+	if snoozeHeartbeat(testargs, time.Minute, false) == nil || snoozeHeartbeat(testargs, time.Minute, true) == nil {
+		t.Errorf("Snooze without stateDir accepted")
+	}
+}
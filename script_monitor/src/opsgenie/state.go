@@ -0,0 +1,44 @@
+package opsgenie
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+//stateFile returns the file in stateDir used for kind of state of the heartbeat with name
+func stateFile(stateDir string, kind string, name string) string {
+	return filepath.Join(stateDir, kind+"-"+url.QueryEscape(name)+".json")
+}
+
+//readState leaves v untouched when the state file does not exist yet
+func readState(path string, v interface{}) error {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, v)
+}
+
+//writeState replaces the state file through a rename so a crash never leaves a partial file behind
+func writeState(path string, v interface{}) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	err = ioutil.WriteFile(tmp, content, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,47 @@
+package opsgenie
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+func printStatus(w io.Writer, heartbeat Heartbeat) {
+	last := "never"
+	if t := lastHeartbeatTime(heartbeat); !t.IsZero() {
+		last = t.Format(time.RFC3339)
+	}
+	rows := [][2]string{
+		{"Name", heartbeat.Name},
+		{"Description", heartbeat.Description},
+		{"Interval", fmt.Sprintf("%d %s", heartbeat.Interval, heartbeat.IntervalUnit)},
+		{"Enabled", fmt.Sprint(heartbeat.Enabled)},
+		{"Expired", fmt.Sprint(heartbeat.Expired)},
+		{"Last heartbeat", last},
+		{"Owner team", heartbeat.OwnerTeam},
+		{"Alert message", heartbeat.AlertMessage},
+		{"Alert tags", strings.Join(heartbeat.AlertTags, ", ")},
+		{"Alert priority", heartbeat.AlertPriority},
+	}
+	for _, row := range rows {
+		fmt.Fprintf(w, "%-16s %s\n", row[0]+":", row[1])
+	}
+}
+
+//showStatus prints the heartbeat as OpsGenie knows it, it returns false when the heartbeat could not be shown
+func showStatus(args OpsArgs, w io.Writer) bool {
+	fields := requestFields("status", args.Name)
+	heartbeat, err := getHeartbeat(args, fields)
+	if err != nil {
+		log.WithFields(fields).Error(err)
+		return false
+	}
+	if heartbeat == nil {
+		return false
+	}
+	printStatus(w, *heartbeat)
+	return true
+}
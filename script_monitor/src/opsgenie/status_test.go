@@ -0,0 +1,32 @@
+package opsgenie
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPrintStatus(t *testing.T) {
+	var out bytes.Buffer
+	printStatus(&out, Heartbeat{Name: "backup", Interval: 1, IntervalUnit: "days", Enabled: true, OwnerTeam: "ops", AlertTags: []string{"db", "nightly"}, AlertPriority: "P2"})
+	for _, line := range []string{"Name:            backup", "Interval:        1 days", "Last heartbeat:  never", "Owner team:      ops", "Alert tags:      db, nightly", "Alert priority:  P2"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("Status should contain [%s] but is\n%s", line, out.String())
+		}
+	}
+}
+
+func TestShowStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"testName","ownerTeam":"ops","alertMessage":"backup missing"}`))
+	}))
+	defer stopTestServer(server)
+	SetAPIURL(server.URL)
+
+	var out bytes.Buffer
+	if !showStatus(testargs, &out) || !strings.Contains(out.String(), "backup missing") {
+		t.Errorf("Status not shown [%s]", out.String())
+	}
+}
@@ -0,0 +1,109 @@
+package opsgenie
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//superviseCommand runs the command as a child and sends the heartbeat every loop interval while it runs and the probe
+//succeeds. Signals are forwarded to the process group of the child. When the child exits cleanly, or because of a forwarded SIGINT or SIGTERM,
+//the heartbeat is disabled, otherwise sending stops so the heartbeat expires or with expire it is expired right away. It returns the exit code of the child.
+func superviseCommand(args OpsArgs, probe string, expire bool, command []string) int {
+	if len(command) == 0 {
+		logAndExit(commandMandatory)
+		return 1
+	}
+	fields := requestFields("run", args.Name)
+	startHeartbeat(args)
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	newProcessGroup(cmd)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+	err := cmd.Start()
+	if err != nil {
+		log.WithFields(fields).Errorf("Could not start command: %v", err)
+		return 127
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	stopPinging := make(chan struct{})
+	go pingWhileRunning(args, probe, requestFields("run", args.Name), stopPinging)
+	stopping := false
+	for {
+		select {
+		case sig := <-signals:
+			log.WithFields(fields).Infof("Forwarding signal [%s]", sig)
+			stopping = stopping || sig == syscall.SIGINT || sig == syscall.SIGTERM
+			signalCommand(cmd, sig)
+		case err := <-done:
+			close(stopPinging)
+			code, err := exitCode(err)
+			fields["exitCode"] = code
+			if err == nil && (code == 0 || stopping) {
+				log.WithFields(fields).Info("Command stopped")
+				disableHeartbeat(args)
+			} else {
+				if err != nil {
+					fields["error"] = err.Error()
+				}
+				log.WithFields(fields).Errorf("Command crashed, stopped sending heartbeat [%s]", args.Name)
+				if expire {
+					expireHeartbeat(args, fmt.Sprintf("Command [%s] crashed with exit code %d", strings.Join(command, " "), code), "")
+				}
+			}
+			return code
+		}
+	}
+}
+
+//pingWhileRunning sends the heartbeat every loop interval while the probe succeeds until stop is closed. It runs apart
+//from superviseCommand, so a slow probe or send does not hold up forwarding signals or handling the exit of the command.
+func pingWhileRunning(args OpsArgs, probe string, fields log.Fields, stop chan struct{}) {
+	ticker := time.NewTicker(args.LoopInterval)
+	defer ticker.Stop()
+	for {
+		probed := probe == "" || ready(probe, args.LoopInterval)
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		if probed {
+			sendHeartbeat(args)
+		} else {
+			log.WithFields(fields).Warnf("Probe [%s] failed, not sending heartbeat [%s]", probe, args.Name)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+//ready runs the probe, an http(s) url must answer with a 2xx status and any other probe is a shell command that must exit 0
+func ready(probe string, timeout time.Duration) bool {
+	if strings.HasPrefix(probe, "http://") || strings.HasPrefix(probe, "https://") {
+		client := &http.Client{Timeout: timeout}
+		resp, err := client.Get(probe)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode >= 200 && resp.StatusCode < 300
+	}
+	code, err := runProbe([]string{"sh", "-c", probe}, timeout)
+	return err == nil && code == 0
+}
@@ -0,0 +1,82 @@
+package opsgenie
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func countPaths(paths []string, path string) int {
+	count := 0
+	for _, p := range paths {
+		if p == path {
+			count++
+		}
+	}
+	return count
+}
+
+func TestSuperviseCommand(t *testing.T) {
+	server, paths := startTestServer()
+	defer stopTestServer(server)
+	args := testargs
+	args.LoopInterval = 20 * time.Millisecond
+
+	if code := superviseCommand(args, "", false, []string{"sleep", "0.1"}); code != 0 {
+		t.Errorf("Clean exit gave [%d]", code)
+	}
+	if countPaths(*paths, "/v1/json/heartbeat/send") < 2 || countPaths(*paths, "/v1/json/heartbeat/disable") != 1 {
+		t.Errorf("Clean exit should send and disable [%v]", *paths)
+	}
+	*paths = nil
+	if code := superviseCommand(args, "", false, []string{"sh", "-c", "sleep 0.05; exit 2"}); code != 2 {
+		t.Errorf("Crash gave [%d]", code)
+	}
+	if countPaths(*paths, "/v1/json/heartbeat/disable") != 0 {
+		t.Errorf("Crash should not disable [%v]", *paths)
+	}
+	*paths = nil
+	superviseCommand(args, "false", false, []string{"sleep", "0.05"})
+	if countPaths(*paths, "/v1/json/heartbeat/send") != 0 {
+		t.Errorf("Failing probe should not send [%v]", *paths)
+	}
+}
+
+func TestReady(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	if !ready(server.URL, time.Second) {
+		t.Error("Probe should be ready on 200")
+	}
+	status = http.StatusServiceUnavailable
+	if ready(server.URL, time.Second) {
+		t.Error("Probe should not be ready on 503")
+	}
+	if !ready("true", time.Second) || ready("exit 1", time.Second) {
+		t.Error("Command probe not correct")
+	}
+}
+
+func TestSuperviseCommandForwardsSignalsDuringProbe(t *testing.T) {
+	server, paths := startTestServer()
+	defer stopTestServer(server)
+	args := testargs
+	args.LoopInterval = 2 * time.Second
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		process, _ := os.FindProcess(os.Getpid())
+		process.Signal(os.Interrupt)
+	}()
+	start := time.Now()
+	superviseCommand(args, "trap \"\" INT; sleep 1", false, []string{"sleep", "5"})
+	if time.Since(start) > 900*time.Millisecond || countPaths(*paths, "/v1/json/heartbeat/disable") != 1 {
+		t.Errorf("Signal not forwarded while the probe ran, stopped after [%s] with requests [%v]", time.Since(start), *paths)
+	}
+}
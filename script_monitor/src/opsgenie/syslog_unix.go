@@ -0,0 +1,25 @@
+//go:build !windows
+// +build !windows
+
+package opsgenie
+
+import (
+	"fmt"
+	"log/syslog"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	logrus_syslog "github.com/Sirupsen/logrus/hooks/syslog"
+)
+
+func createSyslogHook(target string, tag string) (log.Hook, error) {
+	network, address := "", ""
+	if target != "local" {
+		parts := strings.SplitN(target, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("[syslog] should be local or network:address, for example udp:localhost:514")
+		}
+		network, address = parts[0], parts[1]
+	}
+	return logrus_syslog.NewSyslogHook(network, address, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+}
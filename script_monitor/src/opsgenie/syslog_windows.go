@@ -0,0 +1,15 @@
+//go:build windows
+// +build windows
+
+package opsgenie
+
+import (
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//createSyslogHook is not supported, windows has no syslog
+func createSyslogHook(target string, tag string) (log.Hook, error) {
+	return nil, fmt.Errorf("[syslog] is not supported on windows")
+}
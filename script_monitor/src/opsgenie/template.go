@@ -0,0 +1,132 @@
+package opsgenie
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+var (
+	fqdn     string
+	fqdnOnce sync.Once
+)
+
+//templateData is available in heartbeat names and descriptions, like {{.Hostname}}-db-backup or {{.Labels.env}}
+type templateData struct {
+	Labels map[string]string
+}
+
+func (templateData) Hostname() string {
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+//FQDN falls back to the hostname when it can't be resolved, the lookup is only done once
+func (d templateData) FQDN() string {
+	fqdnOnce.Do(func() {
+		hostname := d.Hostname()
+		cname, err := net.LookupCNAME(hostname)
+		if err != nil || cname == "" {
+			fqdn = hostname
+			return
+		}
+		fqdn = strings.TrimSuffix(cname, ".")
+	})
+	return fqdn
+}
+
+func (templateData) User() string {
+	current, err := user.Current()
+	if err != nil {
+		return os.Getenv("USER")
+	}
+	return current.Username
+}
+
+func (templateData) Env() map[string]string {
+	env := make(map[string]string)
+	for _, variable := range os.Environ() {
+		parts := strings.SplitN(variable, "=", 2)
+		if len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+	return env
+}
+
+func expandTemplate(text string, labels map[string]string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New("heartbeat").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var expanded bytes.Buffer
+	err = tmpl.Execute(&expanded, templateData{labels})
+	if err != nil {
+		return "", err
+	}
+	return expanded.String(), nil
+}
+
+//escapeTemplate makes text expand to itself, like a heartbeat name with {{ that is exported from OpsGenie
+func escapeTemplate(text string) string {
+	return strings.Replace(text, "{{", `{{"{{"}}`, -1)
+}
+
+func mergeLabels(global map[string]string, heartbeat map[string]string) map[string]string {
+	labels := make(map[string]string)
+	for k, v := range global {
+		labels[k] = v
+	}
+	for k, v := range heartbeat {
+		labels[k] = v
+	}
+	return labels
+}
+
+//expandTemplates expands the name, description and alert message of all heartbeats in the config. A heartbeat with a
+//template that is not valid keeps its values and the error, so only commands that use that heartbeat fail.
+func (config *Config) expandTemplates() {
+	for i := range config.Heartbeats {
+		heartbeat := &config.Heartbeats[i]
+		labels := mergeLabels(config.Labels, heartbeat.Labels)
+		name, err := expandTemplate(heartbeat.Name, labels)
+		var description, alertMessage string
+		if err == nil {
+			description, err = expandTemplate(heartbeat.Description, labels)
+		}
+		if err == nil {
+			alertMessage, err = expandTemplate(heartbeat.AlertMessage, labels)
+		}
+		if err != nil {
+			heartbeat.templateErr = fmt.Errorf("Heartbeat [%s] has a template that is not valid: %s", heartbeat.Name, err)
+			continue
+		}
+		heartbeat.Name, heartbeat.Description, heartbeat.AlertMessage = name, description, alertMessage
+	}
+}
+
+//expanded returns the heartbeat, or the error when its templates could not be expanded
+func (heartbeat *HeartbeatConfig) expanded() (*HeartbeatConfig, error) {
+	if heartbeat.templateErr != nil {
+		return nil, heartbeat.templateErr
+	}
+	return heartbeat, nil
+}
+
+//templateErrors returns the first heartbeat template that could not be expanded, for commands that use all heartbeats
+func (config *Config) templateErrors() error {
+	for _, heartbeat := range config.Heartbeats {
+		if heartbeat.templateErr != nil {
+			return heartbeat.templateErr
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package opsgenie
+
+import (
+	"os"
+	"testing"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	hostname, _ := os.Hostname()
+	os.Setenv("HEARTBEAT_TEST", "value")
+	defer os.Unsetenv("HEARTBEAT_TEST")
+	labels := map[string]string{"env": "prod"}
+
+	tests := map[string]string{
+		"plain-name":                   "plain-name",
+		"{{.Hostname}}-db-backup":      hostname + "-db-backup",
+		"{{.Labels.env}}-backup":       "prod-backup",
+		"{{.Env.HEARTBEAT_TEST}}-job":  "value-job",
+		"{{if .User}}user{{end}}-job":  "user-job",
+		"{{.FQDN | printf \"%.0s\"}}x": "x",
+	}
+	for text, expected := range tests {
+		expanded, err := expandTemplate(text, labels)
+		if err != nil || expanded != expected {
+			t.Errorf("Template [%s] expanded to [%s] but should be [%s] [%v]", text, expanded, expected, err)
+		}
+	}
+}
+
+func TestExpandTemplateWrong(t *testing.T) {
+	for _, text := range []string{"{{.Hostname", "{{.Labels.missing}}", "{{.Unknown}}"} {
+		if _, err := expandTemplate(text, map[string]string{}); err == nil {
+			t.Errorf("Template [%s] should not be valid", text)
+		}
+	}
+}
+
+func TestConfigExpandTemplates(t *testing.T) {
+	config := &Config{
+		Labels:     map[string]string{"env": "prod", "team": "ops"},
+		Heartbeats: []HeartbeatConfig{{Name: "{{.Labels.env}}-backup", Description: "{{.Labels.team}} backup", Labels: map[string]string{"team": "db"}}},
+	}
+	config.expandTemplates()
+	if config.Heartbeats[0].Name != "prod-backup" || config.Heartbeats[0].Description != "db backup" {
+		t.Errorf("Config not expanded [%+v]", config.Heartbeats[0])
+	}
+}
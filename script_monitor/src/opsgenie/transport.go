@@ -0,0 +1,35 @@
+package opsgenie
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+//httpTransport is shared by all requests so connections to OpsGenie are kept alive and reused between sends
+var httpTransport = &http.Transport{
+	Proxy: proxyForRequest,
+	DialContext: (&net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	ForceAttemptHTTP2:   true,
+	MaxIdleConns:        100,
+	MaxIdleConnsPerHost: 10,
+	IdleConnTimeout:     90 * time.Second,
+	TLSHandshakeTimeout: 10 * time.Second,
+}
+
+var httpClient = &http.Client{Transport: httpTransport}
+
+func getHTTPClient() *http.Client {
+	return httpClient
+}
+
+//withTimeout limits the whole request including reading the body to timeout, the returned cancel must be called
+//once the body is read
+func withTimeout(request *http.Request) (*http.Request, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(request.Context(), timeout)
+	return request.WithContext(ctx), cancel
+}
@@ -0,0 +1,151 @@
+package opsgenie
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//startTLSTestServer answers like startTestServer over TLS and counts the connections opened to it
+func startTLSTestServer(http2 bool) (*httptest.Server, *int64) {
+	var connections int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	server.EnableHTTP2 = http2
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&connections, 1)
+		}
+	}
+	server.StartTLS()
+	SetAPIURL(server.URL)
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	httpTransport.TLSClientConfig = &tls.Config{RootCAs: roots, NextProtos: []string{"h2", "http/1.1"}}
+	return server, &connections
+}
+
+func stopTLSTestServer(server *httptest.Server) {
+	httpTransport.CloseIdleConnections()
+	httpTransport.TLSClientConfig = nil
+	stopTestServer(server)
+}
+
+func TestSharedTransportReusesConnections(t *testing.T) {
+	server, connections := startTLSTestServer(false)
+	defer stopTLSTestServer(server)
+
+	for i := 0; i < 5; i++ {
+		if err := sendHeartbeat(testargs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if *connections != 1 {
+		t.Errorf("Sends opened [%d] connections but should reuse one", *connections)
+	}
+}
+
+func TestSharedTransportHTTP2(t *testing.T) {
+	server, _ := startTLSTestServer(true)
+	defer stopTLSTestServer(server)
+
+	resp, err := getHTTPClient().Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Errorf("Protocol is [%s] but should be HTTP/2", resp.Proto)
+	}
+}
+
+func TestSharedTransportVerifiesCertificates(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	defer stopTestServer(server)
+	SetAPIURL(server.URL)
+
+	if err := sendHeartbeat(testargs); err == nil {
+		t.Error("Heartbeat sent to a server with an untrusted certificate")
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer stopTestServer(server)
+	SetAPIURL(server.URL)
+	defer func(original time.Duration) { timeout = original }(timeout)
+	timeout = 20 * time.Millisecond
+
+	if _, _, err := doHTTPRequest("GET", "/v1/json/heartbeat", nil, nil, log.Fields{}); err == nil {
+		t.Error("Request should time out")
+	}
+}
+
+//perRequestClient is how a client was created for every request before the transport was shared
+func perRequestClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: httpTransport.TLSClientConfig,
+			Proxy:           proxyForRequest,
+			Dial: func(netw, addr string) (net.Conn, error) {
+				conn, err := net.DialTimeout(netw, addr, timeout)
+				if err != nil {
+					return nil, err
+				}
+				conn.SetDeadline(time.Now().Add(timeout))
+				return conn, nil
+			},
+		},
+	}
+}
+
+//benchmarkSends sends to 2000 heartbeats from parallel goroutines like a fleet of loops in one process would
+func benchmarkSends(b *testing.B, newClient func() *http.Client) {
+	server, connections := startTLSTestServer(false)
+	defer stopTLSTestServer(server)
+	level := log.GetLevel()
+	log.SetLevel(log.ErrorLevel)
+	defer log.SetLevel(level)
+
+	var counter int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			args := testargs
+			args.Name = fmt.Sprintf("heartbeat-%d", atomic.AddInt64(&counter, 1)%2000)
+			client := newClient()
+			request, _ := createRequest("POST", "/v1/json/heartbeat/send", nil, mandatoryContentParams(args))
+			resp, err := client.Do(request)
+			if err != nil {
+				b.Fatal(err)
+			}
+			resp.Body.Close()
+			if client != httpClient {
+				client.Transport.(*http.Transport).CloseIdleConnections()
+			}
+		}
+	})
+	b.StopTimer()
+	b.ReportMetric(float64(*connections)/float64(b.N), "conns/op")
+}
+
+func BenchmarkSendSharedTransport(b *testing.B) {
+	benchmarkSends(b, getHTTPClient)
+}
+
+func BenchmarkSendPerRequestTransport(b *testing.B) {
+	benchmarkSends(b, perRequestClient)
+}